@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 
 	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/gjbae1212/findgs/search"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -22,16 +29,29 @@ var (
 
 var (
 	personalGithubToken string
+	githubURL           string
+	githubAPI           string
+	fetchLanguages      bool
+	semanticVectors     bool
+	otherSources        []*search.Source
+)
+
+var (
+	closeOnce sync.Once
 )
 
 var (
 	ErrNotFoundGithubToken = errors.New("[err] Not Found Github Token, you should pass it by \"GITHUB_TOKEN\" ENV or -t option.")
+	ErrNotFoundGiteaURL    = errors.New("[err] Not Found Gitea URL, you should pass it by \"GITEA_URL\" ENV or --gitea-url option.")
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// SIGINT and SIGTERM cancel a context of commands, and findgs shuts down after closing the db.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		color.Red(err.Error())
 		os.Exit(1)
 	}
@@ -40,9 +60,26 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringP("token", "t", "", color.CyanString("Github Token (default is \"GITHUB_TOKEN\" ENV)"))
+	rootCmd.PersistentFlags().String("github-url", "", color.CyanString("Github Enterprise Server URL such as https://github.example.com (default is \"GITHUB_URL\" ENV or https://github.com)"))
+	rootCmd.PersistentFlags().String("github-api", "", color.CyanString("Github API fetching starred, rest or graphql which fetches readme in bulk (default is \"GITHUB_API\" ENV or rest)"))
+	rootCmd.PersistentFlags().String("gitlab-token", "", color.CyanString("Gitlab Token for also searching starred gitlab projects (default is \"GITLAB_TOKEN\" ENV)"))
+	rootCmd.PersistentFlags().String("gitlab-url", "", color.CyanString("Gitlab URL (default is \"GITLAB_URL\" ENV or https://gitlab.com)"))
+	rootCmd.PersistentFlags().String("gitea-token", "", color.CyanString("Gitea(Forgejo) Token for also searching starred gitea repositories (default is \"GITEA_TOKEN\" ENV)"))
+	rootCmd.PersistentFlags().String("gitea-url", "", color.CyanString("Gitea(Forgejo) URL (default is \"GITEA_URL\" ENV)"))
+	rootCmd.PersistentFlags().Bool("languages", false, color.CyanString("Fetch a language breakdown of each repository, which costs an api request per repository (default is \"FINDGS_LANGUAGES\" ENV or false)"))
+	rootCmd.PersistentFlags().Bool("semantic", false, color.CyanString("Compute vectors of repositories locally while indexing, which vector and hybrid modes search. A semantic space of all repositories is computed again whenever any of them changes, which takes longer as they grow (default is \"FINDGS_SEMANTIC\" ENV or false)"))
+	rootCmd.PersistentFlags().Int("fuzziness", search.DefaultFuzziness, color.CyanString("Edit distance of words matched fuzzily when few repositories are matched exactly, 0 turns it off (max 2)"))
+	rootCmd.PersistentFlags().StringToString("boost", nil, color.CyanString("Boosts of fields matched by words without a field (ex name=4,topic=3,owner=2,desc=2,heading=2,readme=1,code=0.5,lang=1)"))
 
 	// mapping viper.
 	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag("boost", rootCmd.PersistentFlags().Lookup("boost"))
+	viper.BindPFlag("languages", rootCmd.PersistentFlags().Lookup("languages"))
+	viper.BindPFlag("fuzziness", rootCmd.PersistentFlags().Lookup("fuzziness"))
+	viper.BindPFlag("semantic", rootCmd.PersistentFlags().Lookup("semantic"))
+	for _, key := range []string{"github-url", "github-api", "gitlab-token", "gitlab-url", "gitea-token", "gitea-url"} {
+		viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(key))
+	}
 
 	// hide help option.
 	rootCmd.SetHelpCommand(&cobra.Command{
@@ -58,9 +95,81 @@ func initConfig() {
 		token = passedToken
 	}
 	personalGithubToken = token
+	githubURL = flagOrEnv("github-url", "GITHUB_URL")
+	githubAPI = flagOrEnv("github-api", "GITHUB_API")
+	if githubAPI != "" && githubAPI != git.APIRest && githubAPI != git.APIGraphQL {
+		panicError(fmt.Errorf("[err] Wrong github api %s, it should be rest or graphql", githubAPI))
+	}
+
+	fetchLanguages = viper.GetBool("languages")
+	if env, err := strconv.ParseBool(os.Getenv("FINDGS_LANGUAGES")); err == nil && env {
+		fetchLanguages = true
+	}
+
+	semanticVectors = viper.GetBool("semantic")
+	if env, err := strconv.ParseBool(os.Getenv("FINDGS_SEMANTIC")); err == nil && env {
+		semanticVectors = true
+	}
+
+	// other providers
+	otherSources = nil
+	if gitlabToken := flagOrEnv("gitlab-token", "GITLAB_TOKEN"); gitlabToken != "" {
+		otherSources = append(otherSources, &search.Source{Provider: git.ProviderGitlab,
+			BaseURL: flagOrEnv("gitlab-url", "GITLAB_URL"), Token: gitlabToken, Languages: fetchLanguages})
+	}
+	if giteaToken := flagOrEnv("gitea-token", "GITEA_TOKEN"); giteaToken != "" {
+		giteaURL := flagOrEnv("gitea-url", "GITEA_URL")
+		if giteaURL == "" {
+			panicError(ErrNotFoundGiteaURL)
+		}
+		otherSources = append(otherSources, &search.Source{Provider: git.ProviderGitea, BaseURL: giteaURL, Token: giteaToken, Languages: fetchLanguages})
+	}
+
+	boosts := map[string]float64{}
+	for field, value := range viper.GetStringMapString("boost") {
+		boost, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			panicError(fmt.Errorf("[err] Wrong boost %s=%s", field, value))
+		}
+		boosts[field] = boost
+	}
+	if err := search.SetFieldBoosts(boosts); err != nil {
+		panicError(err)
+	}
+	if err := search.SetFuzziness(viper.GetInt("fuzziness")); err != nil {
+		panicError(err)
+	}
+}
+
+// flagOrEnv returns a value of flag, or a value of env if the flag isn't passed.
+func flagOrEnv(key, env string) string {
+	if value := viper.GetString(key); value != "" {
+		return value
+	}
+	return os.Getenv(env)
 }
 
+// panicError writes an error to stderr, so stdout only has results, and exits with a non-zero code.
 func panicError(err error) {
-	fmt.Println(color.RedString("%s", err.Error()))
+	fmt.Fprintln(os.Stderr, color.RedString("%s", err.Error()))
 	os.Exit(1)
 }
+
+// closeSearcher closes a searcher once, which unlocks the db.
+func closeSearcher() {
+	closeOnce.Do(func() {
+		if searcher == nil {
+			return
+		}
+		if err := searcher.Close(); err != nil {
+			color.Red("%s", err)
+		}
+	})
+}
+
+// shutdown closes a searcher and exits, when a command is stopped by a signal.
+func shutdown() {
+	color.Yellow("[stop] findgs is stopped by a signal.")
+	closeSearcher()
+	os.Exit(130)
+}
@@ -1,18 +1,23 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-
+	"io"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/briandowns/spinner"
-	"github.com/inancgumus/screen"
 	prompt "github.com/c-bata/go-prompt"
 	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/git"
 	"github.com/gjbae1212/findgs/search"
+	"github.com/inancgumus/screen"
 	"github.com/mattn/go-colorable"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/browser"
@@ -32,14 +37,46 @@ var (
 var (
 	searcher search.Searcher
 	minScore = float64(0.1)
+
+	// maxExplained is a count of repositories whose scores are explained in an interactive CLI.
+	maxExplained = 10
+	// suggestUnder is a count of found repositories, under which a corrected text is suggested.
+	suggestUnder = 3
+
+	// pageSize is a count of repositories per page, which fits the terminal height if it's zero.
+	pageSize int
+	// defaultPageSize is a count of repositories per page if the terminal height is unknown.
+	defaultPageSize = 20
+	// promptLines are lines of a prompt below a page.
+	promptLines = 2
+	// ansiCode matches color codes which aren't shown on a terminal.
+	ansiCode = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 )
 
 var (
-	searchSuggest = prompt.Suggest{Text: "search", Description: "Search starred github repositories which matched text from Readme, description, topic, name ... and so on."}
-	exitSuggest   = prompt.Suggest{Text: "exit", Description: "Good bye."}
-	openSuggest   = prompt.Suggest{Text: "open", Description: "Open a selected repository of found repositories to browser."}
-	listSuggest   = prompt.Suggest{Text: "list", Description: "Show searched repositories recently through search command."}
-	scoreSuggest  = prompt.Suggest{Text: "score", Description: "Set the score that can search repositories equal to or higher than the score.( 0 <= score)"}
+	searchSuggest  = prompt.Suggest{Text: "search", Description: "Search starred github repositories which matched text from Readme, description, topic, name ... and so on."}
+	exitSuggest    = prompt.Suggest{Text: "exit", Description: "Good bye."}
+	openSuggest    = prompt.Suggest{Text: "open", Description: "Open a selected repository of found repositories to browser."}
+	listSuggest    = prompt.Suggest{Text: "list", Description: "Show searched repositories recently through search command."}
+	scoreSuggest   = prompt.Suggest{Text: "score", Description: "Set the score that can search repositories equal to or higher than the score.( 0 <= score)"}
+	explainSuggest = prompt.Suggest{Text: "explain", Description: "Explain how scores of repositories searched by text are computed, which query, field and term contributed how much."}
+	drillSuggest   = prompt.Suggest{Text: "drill", Description: "Narrow down searched repositories by a facet value such as topic:cli and stars:100..999."}
+	sortSuggest    = prompt.Suggest{Text: "sort", Description: "Set an order of searched repositories such as score, stars, forks, starred_at, pushed_at and name."}
+	filterSuggest  = prompt.Suggest{Text: "filter", Description: "Add a range filter such as stars>1000 and pushed>=30d to searches, or clear filters."}
+	nextSuggest    = prompt.Suggest{Text: "next", Description: "Show a next page of searched repositories."}
+	prevSuggest    = prompt.Suggest{Text: "prev", Description: "Show a previous page of searched repositories."}
+	pageSuggest    = prompt.Suggest{Text: "page", Description: "Show a page of searched repositories, or set a page size.(0 fits the terminal height)"}
+	modeSuggest    = prompt.Suggest{Text: "mode", Description: "Set a ranking mode of searches, keyword, vector or hybrid of both.(vector and hybrid need --semantic option)"}
+	similarSuggest = prompt.Suggest{Text: "similar", Description: "Search repositories similar to a selected repository of found repositories by its topics, description and README."}
+
+	pageSizeSuggest = prompt.Suggest{Text: "size", Description: "Set a count of repositories per page.(0 fits the terminal height)"}
+
+	filterClearSuggest = prompt.Suggest{Text: "clear", Description: "Clear all of filters."}
+	filterSuggests     = []prompt.Suggest{
+		{Text: "stars>1000", Description: "Starred more than 1000 times."},
+		{Text: "pushed>=30d", Description: "Pushed within 30 days."},
+		{Text: "starred:2023-01..2023-06", Description: "Starred between dates."},
+	}
 
 	openNumSuggest  = prompt.Suggest{Text: "num", Description: "Open url to browser using num value."}
 	openNameSuggest = prompt.Suggest{Text: "name", Description: "Open url to browser using name value."}
@@ -48,11 +85,23 @@ var (
 var (
 	foundList             []*search.Result
 	foundMap              map[string]*search.Result
+	foundTotal            int
+	pageOffset            int
+	prevOffsets           []int
+	fittedSize            int // a page size fitted to the terminal at the first page, so that every page has the same offsets.
+	foundFacets           map[string][]*search.FacetCount
 	recentlySearchKeyword string
+	similarName           string
+	suggestedText         string
+	sortBy                = search.SortScore
+	rankMode              = search.ModeKeyword
+	filters               []string
 )
 
 var (
 	pt *prompt.Prompt
+	// replCtx is a context of the prompt, which is cancelled by a signal.
+	replCtx = context.Background()
 )
 
 func preRun() execCommand {
@@ -61,23 +110,41 @@ func preRun() execCommand {
 			panicError(ErrNotFoundGithubToken)
 		}
 
-		var err error
-		s := spinner.New(spinner.CharSets[7], 100*time.Millisecond) // Build our new spinner
-		s.Start()
+		loadSearcher(cmd.Context(), os.Stdout)
+	}
+}
 
-		searcher, err = search.NewSearcher(personalGithubToken)
-		if err != nil {
-			panicError(err)
-		}
-		if err := searcher.CreateIndex(); err != nil {
-			panicError(err)
-		}
+// loadSearcher makes a searcher and creates its index while a spinner is written to w.
+// it shuts down if ctx is cancelled by a signal while creating an index.
+func loadSearcher(ctx context.Context, w io.Writer) {
+	var err error
+	s := spinner.New(spinner.CharSets[7], 100*time.Millisecond, spinner.WithWriter(w)) // Build our new spinner
+	s.Start()
+
+	sources := []*search.Source{{Provider: git.ProviderGithub, BaseURL: githubURL, Token: personalGithubToken, API: githubAPI, Languages: fetchLanguages}}
+	searcher, err = search.NewSearcherFromSources(append(sources, otherSources...), search.WithSemantic(semanticVectors))
+	if err != nil {
+		panicError(err)
+	}
+	if err := searcher.CreateIndexContext(ctx); err != nil {
 		s.Stop()
+		if ctx.Err() != nil {
+			shutdown()
+		}
+		panicError(err)
 	}
+	s.Stop()
 }
 
 func run() execCommand {
 	return func(cmd *cobra.Command, args []string) {
+		// the prompt reads ctrl+c as a key, so a signal is SIGTERM or one from another process.
+		replCtx = cmd.Context()
+		go func() {
+			<-replCtx.Done()
+			shutdown()
+		}()
+
 		screen.Clear()
 		screen.MoveTopLeft()
 		total, _ := searcher.TotalDoc()
@@ -98,7 +165,8 @@ func completer(d prompt.Document) []prompt.Suggest {
 	suggests := []prompt.Suggest{}
 	switch {
 	case text == "":
-		suggests = append(suggests, searchSuggest, openSuggest, listSuggest, scoreSuggest, exitSuggest)
+		suggests = append(suggests, searchSuggest, drillSuggest, sortSuggest, filterSuggest, modeSuggest, nextSuggest, prevSuggest, pageSuggest,
+			similarSuggest, explainSuggest, openSuggest, listSuggest, scoreSuggest, exitSuggest)
 	case "exit" != text && strings.Contains("exit", text):
 		suggests = append(suggests, exitSuggest)
 	case "open" != text && strings.Contains("open", text):
@@ -106,10 +174,56 @@ func completer(d prompt.Document) []prompt.Suggest {
 	case "search" != text && strings.Contains("search", text):
 		suggests = append(suggests, searchSuggest)
 		fallthrough
+	case "similar" != text && strings.Contains("similar", text):
+		suggests = append(suggests, similarSuggest)
+		fallthrough
 	case "score" != text && strings.Contains("score", text):
 		suggests = append(suggests, scoreSuggest)
+		fallthrough
+	case "sort" != text && strings.Contains("sort", text):
+		suggests = append(suggests, sortSuggest)
+	case "filter" != text && strings.Contains("filter", text):
+		suggests = append(suggests, filterSuggest)
+	case "mode" != text && strings.Contains("mode", text):
+		suggests = append(suggests, modeSuggest)
+	case "next" != text && strings.Contains("next", text):
+		suggests = append(suggests, nextSuggest)
+	case "prev" != text && strings.Contains("prev", text):
+		suggests = append(suggests, prevSuggest)
+	case "page" != text && strings.Contains("page", text):
+		suggests = append(suggests, pageSuggest)
+	case strings.HasPrefix(text, "page"):
+		suggests = append(suggests, pageSizeSuggest)
+		for i := 1; i <= pageCount(); i++ {
+			suggests = append(suggests, prompt.Suggest{Text: fmt.Sprintf("%d", i)})
+		}
 	case "list" != text && strings.Contains("list", text):
 		suggests = append(suggests, listSuggest)
+	case "explain" != text && strings.Contains("explain", text):
+		suggests = append(suggests, explainSuggest)
+	case "drill" != text && strings.Contains("drill", text):
+		suggests = append(suggests, drillSuggest)
+	case strings.HasPrefix(text, "drill"):
+		for _, name := range search.Facets {
+			for _, count := range foundFacets[name] {
+				suggests = append(suggests, prompt.Suggest{Text: count.Filter, Description: fmt.Sprintf("%s %d", name, count.Count)})
+			}
+		}
+	case strings.HasPrefix(text, "similar"):
+		for i, f := range foundList {
+			suggests = append(suggests, prompt.Suggest{Text: f.FullName, Description: fmt.Sprintf("%d", pageOffset+i+1)})
+		}
+	case strings.HasPrefix(text, "sort"):
+		for _, order := range search.Sorts {
+			suggests = append(suggests, prompt.Suggest{Text: order})
+		}
+	case strings.HasPrefix(text, "filter"):
+		suggests = append(suggests, filterClearSuggest)
+		suggests = append(suggests, filterSuggests...)
+	case strings.HasPrefix(text, "mode"):
+		for _, mode := range search.Modes {
+			suggests = append(suggests, prompt.Suggest{Text: mode})
+		}
 	case strings.HasPrefix(text, "score"):
 		if text == "score" {
 			for i := 0; i < 10; i++ {
@@ -148,7 +262,7 @@ func completer(d prompt.Document) []prompt.Suggest {
 
 		if subText == "num" {
 			for i, _ := range foundList {
-				suggests = append(suggests, prompt.Suggest{Text: fmt.Sprintf("%d", i+1)})
+				suggests = append(suggests, prompt.Suggest{Text: fmt.Sprintf("%d", pageOffset+i+1)})
 			}
 			break
 		} else if subText == "name" {
@@ -163,8 +277,8 @@ func completer(d prompt.Document) []prompt.Suggest {
 			if len(seps) > 1 {
 				numText := strings.TrimSpace(strings.Join(seps[1:], " "))
 				for i, _ := range foundList {
-					if strings.HasPrefix(fmt.Sprintf("%d", i+1), numText) {
-						suggests = append(suggests, prompt.Suggest{Text: fmt.Sprintf("%d", i+1)})
+					if strings.HasPrefix(fmt.Sprintf("%d", pageOffset+i+1), numText) {
+						suggests = append(suggests, prompt.Suggest{Text: fmt.Sprintf("%d", pageOffset+i+1)})
 					}
 				}
 				break
@@ -193,6 +307,7 @@ func executor(t string) {
 	cmd := strings.ToLower(seps[0])
 	switch cmd {
 	case "exit":
+		closeSearcher()
 		color.Green("Good Bye.")
 		os.Exit(0)
 	case "list":
@@ -206,6 +321,7 @@ func executor(t string) {
 		}
 		searchText := strings.ToLower(strings.TrimSpace(strings.Join(subSep[1:], " ")))
 		if ix, err := strconv.Atoi(searchText); err == nil {
+			ix -= pageOffset
 			if ix <= 0 || len(foundList) <= 0 || len(foundList) <= (ix-1) {
 				color.Green("Not matched Repository")
 				return
@@ -235,38 +351,373 @@ func executor(t string) {
 			color.Green("Set score %.3f", minScore)
 		}
 	case "search":
-		recentlySearchKeyword = strings.Join(seps[1:], " ")
-		result, err := searcher.Search(recentlySearchKeyword, minScore)
+		searchRepositories(strings.Join(seps[1:], " "))
+	case "explain":
+		explainText := strings.Join(seps[1:], " ")
+		page, err := searcher.SearchWith(replCtx, explainText, &search.SearchOptions{
+			MinScore: minScore, Sort: sortBy, Filters: filters, Explain: true, Limit: maxExplained})
 		if err != nil {
 			color.Red("%s", err)
 			return
 		}
-
-		foundList = []*search.Result{}
-		foundMap = make(map[string]*search.Result)
-		for _, found := range result {
-			if found.Score >= minScore {
-				foundMap[strings.ToLower(found.FullName)] = found
-				foundList = append(foundList, found)
+		screen.Clear()
+		screen.MoveTopLeft()
+		color.Green("[explain][text] \"%s\"", explainText)
+		fmt.Println()
+		renderExplanationTable(colorable.NewColorableStdout(), page.Results, page.Offset)
+	case "drill":
+		filter := strings.TrimSpace(strings.Join(seps[1:], " "))
+		if recentlySearchKeyword == "" || filter == "" {
+			color.Red("Required a search and a facet value such as topic:cli")
+			return
+		}
+		searchRepositories(drillDown(recentlySearchKeyword, filter))
+	case "sort":
+		order := strings.ToLower(strings.TrimSpace(strings.Join(seps[1:], " ")))
+		if !isSort(order) {
+			color.Red("Wrong sort %s, it should be one of %s", order, strings.Join(search.Sorts, ", "))
+			return
+		}
+		sortBy = order
+		color.Green("Set sort %s", sortBy)
+		refreshSearch()
+	case "mode":
+		mode := strings.ToLower(strings.TrimSpace(strings.Join(seps[1:], " ")))
+		if !isMode(mode) {
+			color.Red("Wrong mode %s, it should be one of %s", mode, strings.Join(search.Modes, ", "))
+			return
+		}
+		rankMode = mode
+		color.Green("Set mode %s", rankMode)
+		refreshSearch()
+	case "filter":
+		filter := strings.TrimSpace(strings.Join(seps[1:], " "))
+		switch {
+		case filter == "":
+			color.Green("[filter] %s", strings.Join(filters, " "))
+			return
+		case strings.ToLower(filter) == "clear":
+			filters = nil
+			color.Green("Clear filters")
+		default:
+			if _, err := search.ParseFilter(filter); err != nil {
+				color.Red("%s", err)
+				return
 			}
+			filters = append(filters, filter)
+			color.Green("Add filter %s", filter)
 		}
-		showSearchedList()
+		refreshSearch()
+	case "next":
+		if (recentlySearchKeyword == "" && similarName == "") || pageOffset+len(foundList) >= foundTotal {
+			color.Red("No next page")
+			return
+		}
+		prevOffsets = append(prevOffsets, pageOffset)
+		showPage(pageOffset + len(foundList))
+	case "prev":
+		if len(prevOffsets) == 0 {
+			color.Red("No previous page")
+			return
+		}
+		offset := prevOffsets[len(prevOffsets)-1]
+		prevOffsets = prevOffsets[:len(prevOffsets)-1]
+		showPage(offset)
+	case "page":
+		pageText := strings.ToLower(strings.TrimSpace(strings.Join(seps[1:], " ")))
+		if strings.HasPrefix(pageText, "size") {
+			size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(pageText, "size")))
+			if err != nil || size < 0 {
+				color.Red("Wrong page size %s, it should be 0 <= size", pageText)
+				return
+			}
+			pageSize = size
+			fittedSize = 0
+			color.Green("Set page size %d", pageSize)
+			if recentlySearchKeyword != "" || similarName != "" {
+				prevOffsets = nil
+				showPage(0)
+			}
+			return
+		}
+		num, err := strconv.Atoi(pageText)
+		if err != nil || num <= 0 || num > pageCount() {
+			color.Red("Wrong page %s, it should be between 1 and %d", pageText, pageCount())
+			return
+		}
+		size := currentPageSize()
+		prevOffsets = nil
+		for offset := 0; offset < (num-1)*size; offset += size {
+			prevOffsets = append(prevOffsets, offset)
+		}
+		showPage((num - 1) * size)
+	case "similar":
+		target := strings.TrimSpace(strings.Join(seps[1:], " "))
+		found, ok := foundMap[strings.ToLower(target)]
+		if ix, err := strconv.Atoi(target); err == nil && ix > pageOffset && ix <= pageOffset+len(foundList) {
+			found, ok = foundList[ix-pageOffset-1], true
+		}
+		if !ok {
+			color.Red("Not matched Repository")
+			return
+		}
+		similarRepositories(found.FullName)
 	default:
 		color.Red("Not Found Command.")
 	}
 }
 
+// searchRepositories searches repositories with facets, and shows the first page of them.
+func searchRepositories(text string) {
+	fittedSize = 0
+	if !loadPage(text, "", 0) {
+		return
+	}
+	prevOffsets = nil
+	suggestedText = suggestText(text, foundList)
+	showSearchedList()
+}
+
+// similarRepositories searches repositories similar to a repository, and shows the first page of them.
+func similarRepositories(fullName string) {
+	fittedSize = 0
+	if !loadPage("", fullName, 0) {
+		return
+	}
+	prevOffsets = nil
+	suggestedText = ""
+	showSearchedList()
+}
+
+// refreshSearch searches recently searched repositories again, such as after a sort is changed.
+func refreshSearch() {
+	switch {
+	case similarName != "":
+		similarRepositories(similarName)
+	case recentlySearchKeyword != "":
+		searchRepositories(recentlySearchKeyword)
+	}
+}
+
+// showPage shows a page of recently searched repositories from offset.
+func showPage(offset int) {
+	if loadPage(recentlySearchKeyword, similarName, offset) {
+		showSearchedList()
+	}
+}
+
+// loadPage searches a page of repositories matched by a text or similar to a repository from offset,
+// which is as long as it can fit the terminal height if a page size is zero.
+func loadPage(text, similar string, offset int) bool {
+	limit := pageSize
+	if limit == 0 {
+		limit = fittedSize
+	}
+	if limit == 0 {
+		limit = fitPageSize()
+	}
+	opts := &search.SearchOptions{
+		MinScore: minScore, Sort: sortBy, Filters: filters, Facets: search.Facets, Offset: offset, Limit: limit, Mode: rankMode}
+	var page *search.Page
+	var err error
+	if similar != "" {
+		page, err = searcher.Similar(replCtx, similar, opts)
+	} else {
+		page, err = searcher.SearchWith(replCtx, text, opts)
+	}
+	if err != nil {
+		color.Red("%s", err)
+		return false
+	}
+	recentlySearchKeyword = text
+	similarName = similar
+	foundFacets = page.Facets
+	foundTotal = page.Total
+	pageOffset = page.Offset
+	setFoundList(page.Results)
+	return true
+}
+
+// setFoundList sets a page of found repositories, which are also looked up by names.
+func setFoundList(list []*search.Result) {
+	foundList = list
+	foundMap = make(map[string]*search.Result)
+	for _, found := range foundList {
+		foundMap[strings.ToLower(found.FullName)] = found
+	}
+}
+
+// fitPageSize returns a maximum count of repositories which can be shown in the terminal height.
+// a row of the table takes 2 lines at least with a row line.
+func fitPageSize() int {
+	_, height := screen.Size()
+	if height <= 0 {
+		return defaultPageSize
+	}
+	if size := (height - promptLines) / 2; size > 0 {
+		return size
+	}
+	return 1
+}
+
+// currentPageSize returns a count of repositories per page, which is a fitted size if it fits the terminal height.
+func currentPageSize() int {
+	if pageSize > 0 {
+		return pageSize
+	}
+	if fittedSize > 0 {
+		return fittedSize
+	}
+	if len(foundList) > 0 {
+		return len(foundList)
+	}
+	return 1
+}
+
+// pageCount returns a count of pages of recently searched repositories.
+func pageCount() int {
+	size := currentPageSize()
+	return (foundTotal + size - 1) / size
+}
+
+// suggestText returns a text whose words are corrected, if few repositories or fuzzily matched ones are found.
+// it returns an empty string if there is nothing to suggest.
+func suggestText(text string, list []*search.Result) string {
+	if len(list) >= suggestUnder && !hasFuzzy(list) {
+		return ""
+	}
+	suggestion, err := searcher.Suggest(replCtx, text)
+	if err != nil {
+		return ""
+	}
+	return suggestion
+}
+
+// hasFuzzy returns whether any of found repositories is matched fuzzily.
+func hasFuzzy(list []*search.Result) bool {
+	for _, found := range list {
+		if found.Fuzzy {
+			return true
+		}
+	}
+	return false
+}
+
+// isSort returns whether an order is one of search.Sorts.
+func isSort(order string) bool {
+	for _, s := range search.Sorts {
+		if s == order {
+			return true
+		}
+	}
+	return false
+}
+
+// isMode returns whether a mode is one of search.Modes.
+func isMode(mode string) bool {
+	for _, m := range search.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// drillDown returns a searching text narrowed by a filter of a facet value.
+func drillDown(text, filter string) string {
+	return fmt.Sprintf("(%s) %s", text, filter)
+}
+
+// showSearchedList shows a page of searched repositories.
+// if a page size is zero, repositories at the end of the first page are left to a next page until it fits the terminal height,
+// and the fitted size is kept for other pages, so that next, prev and page N show the same pages.
 func showSearchedList() {
+	width, height := screen.Size()
+	fitting := pageSize == 0 && fittedSize == 0
+	list := foundList
+	var buf *bytes.Buffer
+	for {
+		buf = &bytes.Buffer{}
+		writeSearchedList(buf, list)
+		if !fitting || height <= 0 || len(list) <= 1 || countLines(buf.String(), width)+promptLines <= height {
+			break
+		}
+		list = list[:len(list)-1]
+	}
+	if fitting && pageOffset+len(list) < foundTotal {
+		fittedSize = len(list)
+	}
+	if len(list) != len(foundList) {
+		setFoundList(list)
+	}
+
 	// clear terminal.
 	screen.Clear()
 	screen.MoveTopLeft()
-	color.Green("[search][text] \"%s\"", recentlySearchKeyword)
-	fmt.Println()
+	io.Copy(colorable.NewColorableStdout(), buf)
+}
 
-	// table writer
-	table := tablewriter.NewWriter(colorable.NewColorableStdout())
-	table.SetHeader([]string{"NUM", "SCORE", "NAME", "URL", "TOPIC", "DESCRIPTION"})
-	table.SetFooter([]string{"", "", "", "", "TOTAL", fmt.Sprintf("%d", len(foundList))})
+// writeSearchedList writes a page of searched repositories with facets to w.
+func writeSearchedList(w io.Writer, list []*search.Result) {
+	if similarName != "" {
+		fmt.Fprintln(w, color.GreenString("[similar][name] \"%s\"", similarName))
+	} else {
+		fmt.Fprintln(w, color.GreenString("[search][text] \"%s\"", recentlySearchKeyword))
+	}
+	if sortBy != search.SortScore || len(filters) != 0 {
+		fmt.Fprintln(w, color.GreenString("[search][sort] %s [filter] %s", sortBy, strings.Join(filters, " ")))
+	}
+	if rankMode != search.ModeKeyword && similarName == "" {
+		fmt.Fprintln(w, color.GreenString("[search][mode] %s", rankMode))
+	}
+	if hasFuzzy(list) {
+		fmt.Fprintln(w, color.YellowString("[fuzzy] few repositories are matched exactly, so similar words are also matched"))
+	}
+	if suggestedText != "" {
+		fmt.Fprintln(w, color.YellowString("did you mean: %s?", suggestedText))
+	}
+	fmt.Fprintln(w)
+
+	renderResultTable(w, list, pageOffset, foundTotal)
+	fmt.Fprintln(w)
+	if len(list) != 0 {
+		fmt.Fprintln(w, color.GreenString("[page] %d..%d of %d (next, prev, page N)", pageOffset+1, pageOffset+len(list), foundTotal))
+	}
+	for _, name := range search.Facets {
+		if counts := foundFacets[name]; len(counts) != 0 {
+			fmt.Fprintln(w, color.GreenString("[%s] %s", name, facetSummary(counts)))
+		}
+	}
+}
+
+// countLines returns a count of lines of a text on a terminal, whose long lines are wrapped by the width.
+func countLines(text string, width int) int {
+	count := 0
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		n := utf8.RuneCountInString(ansiCode.ReplaceAllString(line, ""))
+		if width <= 0 || n <= width {
+			count++
+			continue
+		}
+		count += (n + width - 1) / width
+	}
+	return count
+}
+
+// facetSummary returns values of a facet with their counts such as "go(3) rust(2)".
+func facetSummary(counts []*search.FacetCount) string {
+	var values []string
+	for _, count := range counts {
+		values = append(values, fmt.Sprintf("%s(%d)", count.Value, count.Count))
+	}
+	return strings.Join(values, " ")
+}
+
+// renderResultTable writes a page of found repositories from offset to w as a table, with a total of them.
+func renderResultTable(w io.Writer, list []*search.Result, offset, total int) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"NUM", "SCORE", "NAME", "LANG", "LICENSE", "URL", "TOPIC", "DESCRIPTION"})
+	table.SetFooter([]string{"", "", "", "", "", "", "TOTAL", fmt.Sprintf("%d", total)})
 	table.SetBorder(false)
 	table.SetAutoMergeCells(true)
 	table.SetRowLine(true)
@@ -276,6 +727,8 @@ func showSearchedList() {
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgHiBlueColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgBlueColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgBlueColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgMagentaColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgYellowColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgRedColor})
@@ -285,27 +738,109 @@ func showSearchedList() {
 		tablewriter.Colors{tablewriter.Bold},
 		tablewriter.Colors{},
 		tablewriter.Colors{},
+		tablewriter.Colors{},
+		tablewriter.Colors{},
 		tablewriter.Colors{tablewriter.Bold})
 	table.SetFooterColor(
-		tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{},
+		tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.BgRedColor, tablewriter.FgWhiteColor},
 		tablewriter.Colors{tablewriter.BgGreenColor, tablewriter.FgHiWhiteColor})
 
 	data := [][]string{}
-	for i, found := range foundList {
+	for i, found := range list {
 		data = append(data, []string{
-			fmt.Sprintf("%d", i+1),
+			fmt.Sprintf("%d", offset+i+1),
 			fmt.Sprintf("%f", found.Score),
-			found.FullName,
+			nameWithFlags(found),
+			found.Language,
+			found.License,
 			found.Url,
 			fmt.Sprintf("%s", found.Topics),
-			found.Description,
+			descriptionWithSnippet(found),
 		})
 	}
 	table.AppendBulk(data)
 	table.Render()
 }
 
+// renderExplanationTable writes contributions of queries, fields and terms to scores of found repositories from offset to w as a table,
+// which are numbered as the same as a table of them.
+func renderExplanationTable(w io.Writer, list []*search.Result, offset int) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"NUM", "NAME", "SCORE", "QUERY", "KIND", "FIELD", "TERM", "CONTRIBUTION"})
+	table.SetBorder(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgGreenColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgHiBlueColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgMagentaColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgBlueColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgBlueColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgYellowColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.BgRedColor})
+
+	data := [][]string{}
+	for i, found := range list {
+		for _, c := range found.Contributions {
+			data = append(data, []string{
+				fmt.Sprintf("%d", offset+i+1),
+				found.FullName,
+				fmt.Sprintf("%f", found.Score),
+				c.Query,
+				c.Kind,
+				c.Field,
+				c.Term,
+				fmt.Sprintf("%f", c.Score),
+			})
+		}
+	}
+	table.AppendBulk(data)
+	table.Render()
+}
+
+// descriptionWithSnippet returns a description with a snippet of a README around matched terms, which are colored on a terminal.
+func descriptionWithSnippet(found *search.Result) string {
+	before, after := "\x1b[33m", "\x1b[39m"
+	if color.NoColor {
+		before, after = "", ""
+	}
+	snippet := found.Snippet(before, after)
+	switch {
+	case snippet == "":
+		return found.Description
+	case found.Description == "":
+		return "... " + snippet + " ..."
+	default:
+		return found.Description + "\n\n... " + snippet + " ..."
+	}
+}
+
+// nameWithFlags returns a full name of a repository with flags such as archived and fork.
+func nameWithFlags(found *search.Result) string {
+	var flags []string
+	for _, flag := range []struct {
+		name string
+		on   bool
+	}{
+		{name: "archived", on: found.Archived},
+		{name: "disabled", on: found.Disabled},
+		{name: "fork", on: found.Fork},
+		{name: "template", on: found.Template},
+	} {
+		if flag.on {
+			flags = append(flags, flag.name)
+		}
+	}
+	if len(flags) == 0 {
+		return found.FullName
+	}
+	return fmt.Sprintf("%s\n(%s)", found.FullName, strings.Join(flags, ", "))
+}
+
 func init() {
+	runCommand.Flags().IntVar(&pageSize, "page-size", 0, color.CyanString("Count of repositories per page (0 fits the terminal height)"))
 	rootCmd.AddCommand(runCommand)
 }
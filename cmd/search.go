@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/search"
+	"github.com/mattn/go-colorable"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchCommand = &cobra.Command{
+		Use:    "search [text]",
+		Short:  color.YellowString("Search starred Github repositories once and print results."),
+		Long:   color.YellowString("Search starred Github repositories once and print results as a table, json, jsonl or tsv.\nIt's useful for piping results into other tools such as jq, fzf and shell scripts."),
+		Args:   cobra.MinimumNArgs(1),
+		PreRun: preSearch(),
+		Run:    searchRun(),
+	}
+)
+
+var (
+	outputFormat   string
+	searchMinScore float64
+	searchLimit    int
+	searchOffset   int
+	searchExplain  bool
+	searchSort     string
+	searchFilters  []string
+	searchMode     string
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+	formatTSV   = "tsv"
+)
+
+var (
+	ErrUnknownFormat = fmt.Errorf("[err] Unknown format, you should pass one of %s, %s, %s, %s.", formatTable, formatJSON, formatJSONL, formatTSV)
+)
+
+type searchOutput struct {
+	Num             int                 `json:"num"`
+	Score           float64             `json:"score"`
+	RawScore        float64             `json:"raw_score"`
+	Provider        string              `json:"provider"`
+	FullName        string              `json:"full_name"`
+	Url             string              `json:"url"`
+	Description     string              `json:"description"`
+	Topics          []string            `json:"topics"`
+	Language        string              `json:"language"`
+	Languages       map[string]int      `json:"languages,omitempty"`
+	License         string              `json:"license"`
+	Homepage        string              `json:"homepage"`
+	DefaultBranch   string              `json:"default_branch"`
+	Archived        bool                `json:"archived"`
+	Disabled        bool                `json:"disabled"`
+	Fork            bool                `json:"fork"`
+	Template        bool                `json:"template"`
+	StargazersCount int                 `json:"stargazers_count"`
+	ForksCount      int                 `json:"forks_count"`
+	OpenIssuesCount int                 `json:"open_issues_count"`
+	Size            int                 `json:"size"`
+	StarredAt       string              `json:"starred_at"`
+	PushedAt        string              `json:"pushed_at"`
+	Snippet         string              `json:"snippet,omitempty"`
+	Highlights      map[string][]string `json:"highlights,omitempty"`
+	Fuzzy           bool                `json:"fuzzy,omitempty"`
+
+	Contributions []*search.Contribution `json:"contributions,omitempty"`
+	Explanation   *search.Explanation    `json:"explanation,omitempty"`
+}
+
+func preSearch() execCommand {
+	return func(cmd *cobra.Command, args []string) {
+		if personalGithubToken == "" {
+			panicError(ErrNotFoundGithubToken)
+		}
+
+		switch outputFormat {
+		case formatTable, formatJSON, formatJSONL, formatTSV:
+		default:
+			panicError(ErrUnknownFormat)
+		}
+		if !isMode(strings.ToLower(searchMode)) {
+			panicError(fmt.Errorf("[err] Wrong mode %s, it should be one of %s", searchMode, strings.Join(search.Modes, ", ")))
+		}
+		// vectors are computed for vector and hybrid modes even without --semantic option.
+		if strings.ToLower(searchMode) != search.ModeKeyword {
+			semanticVectors = true
+		}
+
+		// progress messages go to stderr, so stdout only has results.
+		color.Output = colorable.NewColorableStderr()
+		loadSearcher(cmd.Context(), os.Stderr)
+	}
+}
+
+func searchRun() execCommand {
+	return func(cmd *cobra.Command, args []string) {
+		text := strings.Join(args, " ")
+		page, err := searcher.SearchWith(cmd.Context(), text, &search.SearchOptions{
+			MinScore: searchMinScore, Sort: searchSort, Filters: searchFilters, Explain: searchExplain,
+			Offset: searchOffset, Limit: searchLimit, Mode: searchMode})
+		if err != nil {
+			if cmd.Context().Err() != nil {
+				shutdown()
+			}
+			panicError(err)
+		}
+
+		if err := writeResults(colorable.NewColorableStdout(), outputFormat, page, searchExplain); err != nil {
+			panicError(err)
+		}
+		// a suggestion goes to stderr, so stdout only has results.
+		if suggestion := suggestText(text, page.Results); suggestion != "" {
+			color.Yellow("did you mean: %s?", suggestion)
+		}
+		closeSearcher()
+	}
+}
+
+// writeResults writes a page of found repositories to w with the format.
+// a table is followed by a table of score contributions if explain is true.
+func writeResults(w io.Writer, format string, page *search.Page, explain bool) error {
+	list := page.Results
+	switch format {
+	case formatTable:
+		renderResultTable(w, list, page.Offset, page.Total)
+		if explain {
+			fmt.Fprintln(w)
+			renderExplanationTable(w, list, page.Offset)
+		}
+	case formatJSON:
+		outputs := []*searchOutput{}
+		for i, found := range list {
+			outputs = append(outputs, toSearchOutput(page.Offset+i+1, found))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(outputs); err != nil {
+			return fmt.Errorf("[err] writeResults %w", err)
+		}
+	case formatJSONL:
+		enc := json.NewEncoder(w)
+		for i, found := range list {
+			if err := enc.Encode(toSearchOutput(page.Offset+i+1, found)); err != nil {
+				return fmt.Errorf("[err] writeResults %w", err)
+			}
+		}
+	case formatTSV:
+		fmt.Fprintln(w, strings.Join([]string{"num", "score", "full_name", "url", "topics", "description"}, "\t"))
+		for i, found := range list {
+			fmt.Fprintln(w, strings.Join([]string{
+				fmt.Sprintf("%d", page.Offset+i+1),
+				fmt.Sprintf("%f", found.Score),
+				tsvEscape(found.FullName),
+				tsvEscape(found.Url),
+				tsvEscape(strings.Join(found.Topics, ",")),
+				tsvEscape(found.Description),
+			}, "\t"))
+		}
+	default:
+		return ErrUnknownFormat
+	}
+	return nil
+}
+
+func toSearchOutput(num int, found *search.Result) *searchOutput {
+	topics := found.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	return &searchOutput{
+		Num:             num,
+		Score:           found.Score,
+		RawScore:        found.RawScore,
+		Provider:        found.Provider,
+		FullName:        found.FullName,
+		Url:             found.Url,
+		Description:     found.Description,
+		Topics:          topics,
+		Language:        found.Language,
+		Languages:       found.Languages,
+		License:         found.License,
+		Homepage:        found.Homepage,
+		DefaultBranch:   found.DefaultBranch,
+		Archived:        found.Archived,
+		Disabled:        found.Disabled,
+		Fork:            found.Fork,
+		Template:        found.Template,
+		StargazersCount: found.StargazersCount,
+		ForksCount:      found.ForksCount,
+		OpenIssuesCount: found.OpenIssuesCount,
+		Size:            found.Size,
+		StarredAt:       found.StarredAt.Format(time.RFC3339),
+		PushedAt:        found.PushedAt.Format(time.RFC3339),
+		Snippet:         found.Snippet("", ""),
+		Highlights:      found.Fragments,
+		Fuzzy:           found.Fuzzy,
+		Contributions:   found.Contributions,
+		Explanation:     found.Explanation,
+	}
+}
+
+// tsvEscape replaces characters which break a tsv row.
+func tsvEscape(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+func init() {
+	searchCommand.Flags().StringVarP(&outputFormat, "format", "f", formatTable, color.CyanString("Output format (table, json, jsonl, tsv)"))
+	searchCommand.Flags().Float64VarP(&searchMinScore, "min-score", "s", minScore, color.CyanString("Print repositories equal to or higher than the score"))
+	searchCommand.Flags().BoolVar(&searchExplain, "explain", false, color.CyanString("Print how scores are computed, which query, field and term contributed how much"))
+	searchCommand.Flags().StringVar(&searchSort, "sort", search.SortScore, color.CyanString("Order of printed repositories (%s)", strings.Join(search.Sorts, ", ")))
+	searchCommand.Flags().StringVar(&searchMode, "mode", search.ModeKeyword, color.CyanString("Ranking mode of repositories, keyword, vector or hybrid of keyword and vector (%s)", strings.Join(search.Modes, ", ")))
+	searchCommand.Flags().StringArrayVar(&searchFilters, "filter", nil, color.CyanString("Range filter which can be repeated (ex stars>1000, pushed>=30d, starred:2023-01..2023-06)"))
+	searchCommand.Flags().IntVarP(&searchLimit, "limit", "l", 0, color.CyanString("Maximum number of printed repositories (0 is unlimited)"))
+	searchCommand.Flags().IntVar(&searchOffset, "offset", 0, color.CyanString("Number of skipped repositories, which pages results with --limit"))
+	rootCmd.AddCommand(searchCommand)
+}
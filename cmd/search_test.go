@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gjbae1212/findgs/git"
+	"github.com/gjbae1212/findgs/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteResults(t *testing.T) {
+	assert := assert.New(t)
+
+	results := []*search.Result{
+		{Starred: &git.Starred{FullName: "spf13/cobra", Url: "https://github.com/spf13/cobra", Topics: []string{"cli", "go"},
+			Description: "A Commander\tfor modern\nGo CLI"}, Score: 0.5},
+		{Starred: &git.Starred{FullName: "spf13/viper", Url: "https://github.com/spf13/viper", Description: "Go configuration"}, Score: 0.25},
+	}
+
+	tests := map[string]struct {
+		format string
+		page   *search.Page
+		output string
+		isErr  bool
+	}{
+		"json empty":  {format: formatJSON, page: &search.Page{}, output: "[]\n"},
+		"jsonl empty": {format: formatJSONL, page: &search.Page{}, output: ""},
+		"tsv": {format: formatTSV, page: &search.Page{Results: results, Total: 2}, output: "num\tscore\tfull_name\turl\ttopics\tdescription\n" +
+			"1\t0.500000\tspf13/cobra\thttps://github.com/spf13/cobra\tcli,go\tA Commander for modern Go CLI\n" +
+			"2\t0.250000\tspf13/viper\thttps://github.com/spf13/viper\t\tGo configuration\n"},
+		"unknown": {format: "xml", page: &search.Page{}, isErr: true},
+	}
+
+	for name, t := range tests {
+		buf := &bytes.Buffer{}
+		err := writeResults(buf, t.format, t.page, false)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(t.output, buf.String(), name)
+		}
+	}
+
+	// repositories are numbered from an offset.
+	for format, nums := range map[string][]int{formatJSON: {11, 12}, formatJSONL: {11, 12}} {
+		buf := &bytes.Buffer{}
+		assert.NoError(writeResults(buf, format, &search.Page{Results: results, Total: 12, Offset: 10}, false), format)
+
+		var outputs []*searchOutput
+		if format == formatJSON {
+			assert.NoError(json.Unmarshal(buf.Bytes(), &outputs), format)
+		} else {
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				output := &searchOutput{}
+				assert.NoError(json.Unmarshal([]byte(line), output), format)
+				outputs = append(outputs, output)
+			}
+		}
+		var got []int
+		for _, output := range outputs {
+			got = append(got, output.Num)
+		}
+		assert.Equal(nums, got, format)
+	}
+
+	// contributions are numbered as the same as a table of results.
+	explained := []*search.Result{{Starred: results[0].Starred, Score: 0.5,
+		Contributions: []*search.Contribution{{Query: "cobra", Kind: "term", Field: "name", Term: "cobra", Score: 0.5}}}}
+	buf := &bytes.Buffer{}
+	assert.NoError(writeResults(buf, formatTable, &search.Page{Results: explained, Total: 11, Offset: 10}, true))
+	var rows []string
+	ansi := regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	for _, line := range strings.Split(ansi.ReplaceAllString(buf.String(), ""), "\n") {
+		if strings.Contains(line, "spf13/cobra") {
+			rows = append(rows, strings.Fields(line)[0])
+		}
+	}
+	assert.Equal([]string{"11", "11"}, rows)
+}
+
+func TestToSearchOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	starredAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := map[string]struct {
+		input  *search.Result
+		output *searchOutput
+	}{
+		"empty topics": {
+			input: &search.Result{Starred: &git.Starred{FullName: "spf13/cobra", StarredAt: git.JsonTime{Time: starredAt}}, Score: 0.5, RawScore: 1.2},
+			output: &searchOutput{Num: 3, Score: 0.5, RawScore: 1.2, FullName: "spf13/cobra", Topics: []string{},
+				StarredAt: "2023-01-02T03:04:05Z", PushedAt: "0001-01-01T00:00:00Z"},
+		},
+		"snippet": {
+			input: &search.Result{Starred: &git.Starred{FullName: "spf13/viper", Topics: []string{"config"}},
+				Fragments: map[string][]string{"readme": {"Go <mark>configuration</mark> &amp; more"}}},
+			output: &searchOutput{Num: 3, FullName: "spf13/viper", Topics: []string{"config"}, StarredAt: "0001-01-01T00:00:00Z",
+				PushedAt: "0001-01-01T00:00:00Z", Snippet: "Go configuration & more",
+				Highlights: map[string][]string{"readme": {"Go <mark>configuration</mark> &amp; more"}}},
+		},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, toSearchOutput(3, t.input), name)
+	}
+}
+
+func TestTsvEscape(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input  string
+		output string
+	}{
+		"plain":   {input: "spf13/cobra", output: "spf13/cobra"},
+		"tab":     {input: "a\tb", output: "a b"},
+		"newline": {input: "a\r\nb\nc", output: "a  b c"},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, tsvEscape(t.input), name)
+	}
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	// maxCachedBodySize is the largest body of a response which is cached.
+	maxCachedBodySize = 1 << 20
+)
+
+// Cache stores responses of conditional requests, such as a bucket of local db.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+type cachedResponse struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// conditionalTransport sends GET requests with If-None-Match and If-Modified-Since of cached responses,
+// and returns a cached response for 304 Not Modified which isn't charged against a rate limit.
+type conditionalTransport struct {
+	base  http.RoundTripper
+	cache Cache
+}
+
+// newConditionalTransport returns a conditionalTransport sending requests with base, http.DefaultTransport is used if it's nil.
+// requests aren't cached until a cache is set.
+func newConditionalTransport(base http.RoundTripper) *conditionalTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &conditionalTransport{base: base}
+}
+
+// RoundTrip sends a conditional request if a response of the request is cached.
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cache == nil || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	var cached *cachedResponse
+	if data, ok := t.cache.Get(key); ok {
+		if err := json.Unmarshal(data, &cached); err != nil {
+			cached = nil
+		}
+	}
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		// headers of 304 such as a rate limit are fresher than cached ones.
+		header := cached.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		for k, v := range resp.Header {
+			header[k] = v
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = http.StatusText(http.StatusOK)
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		return resp, nil
+	case resp.StatusCode == http.StatusOK:
+		etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag == "" && lastModified == "" {
+			return resp, nil
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxCachedBodySize {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return resp, nil
+		}
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		if data, err := json.Marshal(&cachedResponse{
+			ETag: etag, LastModified: lastModified, Header: resp.Header, Body: body,
+		}); err == nil {
+			t.cache.Set(key, data)
+		}
+		return resp, nil
+	default:
+		return resp, nil
+	}
+}
+
+// cacheKey returns a key of a request, which differs by credentials and media types without keeping them.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	for _, name := range []string{"Authorization", "PRIVATE-TOKEN", "Accept"} {
+		h.Write([]byte(req.Header.Get(name)))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(req.URL.String()))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memCache struct {
+	sync.Mutex
+	values map[string][]byte
+}
+
+func (c *memCache) Get(key string) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+func (c *memCache) Set(key string, value []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func TestConditionalTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	var full, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("X-RateLimit-Remaining", "4998")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Next-Page", "2")
+		fmt.Fprint(w, `[{"id":1}]`)
+	}))
+	defer server.Close()
+
+	tests := map[string]struct {
+		token       string
+		full        int32
+		notModified int32
+		remaining   int
+	}{
+		"first":       {token: "token", full: 1, remaining: 4998},
+		"cached":      {token: "token", full: 1, notModified: 1, remaining: 4999},
+		"other token": {token: "other", full: 2, notModified: 1, remaining: 4998},
+	}
+
+	cache := &memCache{values: map[string][]byte{}}
+	for _, name := range []string{"first", "cached", "other token"} {
+		t := tests[name]
+		c := newRestClient(server.URL, "PRIVATE-TOKEN", t.token)
+		c.SetCache(cache)
+
+		body, header, err := c.get(context.Background(), "/projects", nil)
+		assert.NoError(err, name)
+		assert.Equal(`[{"id":1}]`, string(body), name)
+		assert.Equal("2", header.Get("X-Next-Page"), name)
+		assert.Equal(t.full, atomic.LoadInt32(&full), name)
+		assert.Equal(t.notModified, atomic.LoadInt32(&notModified), name)
+		assert.Equal(t.remaining, c.RateLimit().Remaining, name)
+	}
+
+	// a token isn't kept in a cache.
+	for key, value := range cache.values {
+		assert.NotContains(key, "token")
+		assert.NotContains(string(value), "token")
+	}
+}
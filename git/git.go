@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
 	github "github.com/google/go-github/v29/github"
 	"golang.org/x/oauth2"
 )
@@ -12,15 +16,45 @@ var (
 	ErrInvalidParam   = errors.New("[git][err] parameters invalids")
 	ErrApiQuotaExceed = errors.New("[git][err] api-quota exceeds")
 	ErrNotFound       = errors.New("[git][err] not found")
+)
 
-	githubRateLimit *github.RateLimitError
+const (
+	ProviderGithub = "github"
+	ProviderGitlab = "gitlab"
+	ProviderGitea  = "gitea"
+
+	githubHost = "github.com"
+)
+
+var (
+	// readmeCandidates are file names of readme which are tried in order, when a provider doesn't tell it.
+	readmeCandidates = []string{"README.md", "README", "README.rst", "README.txt", "readme.md"}
 )
 
 type Git interface {
-	User() (*User, error)
-	SetReadme(starred []*Starred)
-	ListStarredAll() ([]*Starred, error)
-	ListReadme(owners []string, repos []string) ([]*Readme, error)
+	Provider() string
+	Host() string
+	RateLimit() RateLimit
+	SetCache(cache Cache)
+
+	// in-flight requests and workers are cancelled by ctx.
+	UserContext(ctx context.Context) (*User, error)
+	SetReadmeContext(ctx context.Context, starred []*Starred)
+	ListStarredAllContext(ctx context.Context) ([]*Starred, error)
+	ListStarredPageContext(ctx context.Context, cursor string) (starred []*Starred, next string, err error)
+	ListReadmeContext(ctx context.Context, owners []string, repos []string) ([]*Readme, error)
+	SetLanguagesContext(ctx context.Context, starred []*Starred) error
+}
+
+// StarredCounter is a client which counts starred,
+// so that a sync can check whether pages shifted during it skipped some of starred.
+type StarredCounter interface {
+	CountStarredContext(ctx context.Context) (int, error)
+}
+
+// PagesLister is a client whose pages are numbered, so that pages from a cursor are fetched concurrently.
+type PagesLister interface {
+	ListStarredPagesContext(ctx context.Context, cursor string, n int) (starred []*Starred, next string, err error)
 }
 
 // NewGit returns a github client by a personal access token.
@@ -30,8 +64,60 @@ func NewGit(token string) (Git, error) {
 		return nil, fmt.Errorf("[err] NewGit %w", ErrInvalidParam)
 	}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	client := github.NewClient(oauth2.NewClient(context.Background(), ts))
+	cond := newConditionalTransport(nil)
+	sched := newScheduler(cond)
+	client := github.NewClient(newOauth2Client(token, sched))
+
+	return &wrapper{Client: client, host: githubHost, sched: sched, cond: cond}, nil
+}
+
+// NewGithubEnterprise returns a github enterprise server client by a personal access token.
+// baseURL is the address of the server such as https://github.example.com.
+func NewGithubEnterprise(baseURL, token string) (Git, error) {
+	if token == "" || baseURL == "" {
+		return nil, fmt.Errorf("[err] NewGithubEnterprise %w", ErrInvalidParam)
+	}
+	host := hostOf(baseURL)
+	if host == "" {
+		return nil, fmt.Errorf("[err] NewGithubEnterprise %w", ErrInvalidParam)
+	}
+
+	cond := newConditionalTransport(nil)
+	sched := newScheduler(cond)
+	client, err := github.NewEnterpriseClient(baseURL, baseURL, newOauth2Client(token, sched))
+	if err != nil {
+		return nil, fmt.Errorf("[err] NewGithubEnterprise %w", err)
+	}
+	uploadURL, err := url.Parse(strings.TrimRight(baseURL, "/") + "/api/uploads/")
+	if err != nil {
+		return nil, fmt.Errorf("[err] NewGithubEnterprise %w", err)
+	}
+	client.UploadURL = uploadURL
 
-	return &wrapper{Client: client, token: token}, nil
+	return &wrapper{Client: client, host: host, sched: sched, cond: cond}, nil
+}
+
+// NewProviderGit returns a git client of a provider such as github, gitlab and gitea.
+// baseURL is used for a self-hosted provider, and a default address is used if it's empty.
+func NewProviderGit(provider, baseURL, token string) (Git, error) {
+	switch provider {
+	case ProviderGithub:
+		if baseURL == "" || hostOf(baseURL) == githubHost {
+			return NewGit(token)
+		}
+		return NewGithubEnterprise(baseURL, token)
+	case ProviderGitlab:
+		return NewGitlab(baseURL, token)
+	case ProviderGitea:
+		return NewGitea(baseURL, token)
+	default:
+		return nil, fmt.Errorf("[err] NewProviderGit unknown provider %s %w", provider, ErrInvalidParam)
+	}
+}
+
+// newOauth2Client returns a http client sending requests with a token through a scheduler.
+func newOauth2Client(token string, sched *scheduler) *http.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: sched})
+	return oauth2.NewClient(ctx, ts)
 }
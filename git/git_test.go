@@ -24,6 +24,59 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNewGithubEnterprise(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		baseURL string
+		token   string
+		host    string
+		apiURL  string
+		isErr   bool
+	}{
+		"success":     {baseURL: "https://github.example.com", token: "token", host: "github.example.com", apiURL: "https://github.example.com/api/v3/"},
+		"empty url":   {token: "token", isErr: true},
+		"empty token": {baseURL: "https://github.example.com", isErr: true},
+	}
+
+	for name, t := range tests {
+		g, err := NewGithubEnterprise(t.baseURL, t.token)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(ProviderGithub, g.Provider(), name)
+			assert.Equal(t.host, g.Host(), name)
+			assert.Equal(t.apiURL, g.(*wrapper).BaseURL.String(), name)
+		}
+	}
+}
+
+func TestNewProviderGit(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		provider string
+		baseURL  string
+		host     string
+		isErr    bool
+	}{
+		"github":            {provider: ProviderGithub, host: "github.com"},
+		"github.com":        {provider: ProviderGithub, baseURL: "https://github.com", host: "github.com"},
+		"github enterprise": {provider: ProviderGithub, baseURL: "https://github.example.com", host: "github.example.com"},
+		"gitlab":            {provider: ProviderGitlab, host: "gitlab.com"},
+		"gitea":             {provider: ProviderGitea, baseURL: "https://codeberg.org", host: "codeberg.org"},
+		"unknown":           {provider: "svn", isErr: true},
+	}
+
+	for name, t := range tests {
+		g, err := NewProviderGit(t.provider, t.baseURL, "token")
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(t.provider, g.Provider(), name)
+			assert.Equal(t.host, g.Host(), name)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	if os.Getenv("GITHUB_TOKEN") != "" {
 		os.Exit(m.Run())
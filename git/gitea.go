@@ -0,0 +1,240 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	giteaPerPage = 50
+)
+
+type giteaUser struct {
+	Login       string    `json:"login"`
+	AvatarURL   string    `json:"avatar_url"`
+	HTMLURL     string    `json:"html_url"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+}
+
+type giteaRepository struct {
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	HTMLURL       string    `json:"html_url"`
+	Description   string    `json:"description"`
+	Topics        []string  `json:"topics"`
+	StarsCount    int       `json:"stars_count"`
+	ForksCount    int       `json:"forks_count"`
+	WatchersCount int       `json:"watchers_count"`
+	OpenIssues    int       `json:"open_issues_count"`
+	Size          int       `json:"size"`
+	Language      string    `json:"language"`
+	Website       string    `json:"website"`
+	DefaultBranch string    `json:"default_branch"`
+	Archived      bool      `json:"archived"`
+	Fork          bool      `json:"fork"`
+	Template      bool      `json:"template"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+type gitea struct {
+	*restClient
+	host string
+}
+
+// NewGitea returns a gitea(or forgejo) client by an access token.
+// baseURL is the address of a gitea instance such as https://codeberg.org.
+// reference: https://docs.gitea.com/development/api-usage
+func NewGitea(baseURL, token string) (Git, error) {
+	if token == "" || baseURL == "" {
+		return nil, fmt.Errorf("[err] NewGitea %w", ErrInvalidParam)
+	}
+	host := hostOf(baseURL)
+	if host == "" {
+		return nil, fmt.Errorf("[err] NewGitea %w", ErrInvalidParam)
+	}
+
+	client := newRestClient(strings.TrimRight(baseURL, "/")+"/api/v1", "Authorization", "token "+token)
+	return &gitea{restClient: client, host: host}, nil
+}
+
+// Provider returns a name of provider.
+func (g *gitea) Provider() string {
+	return ProviderGitea
+}
+
+// Host returns a host of gitea.
+func (g *gitea) Host() string {
+	return g.host
+}
+
+// UserContext returns gitea user object.
+func (g *gitea) UserContext(ctx context.Context) (*User, error) {
+	var user *giteaUser
+	if _, err := g.getJSON(ctx, "/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("[err] User %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("[err] User %w", ErrNotFound)
+	}
+	return &User{
+		Owner:     user.Login,
+		AvatarURL: user.AvatarURL,
+		Url:       user.HTMLURL,
+		Bio:       user.Description,
+		CreatedAt: JsonTime{user.Created},
+		CachedAt:  JsonTime{time.Now()},
+	}, nil
+}
+
+// ListStarredAllContext returns all of starred repositories.
+func (g *gitea) ListStarredAllContext(ctx context.Context) ([]*Starred, error) {
+	starred, err := listStarredByCursor(ctx, g.ListStarredPageContext)
+	if err != nil {
+		return starred, fmt.Errorf("[err] ListStarredAll %w", err)
+	}
+	return starred, nil
+}
+
+// ListStarredPageContext returns starred repositories of a page from a cursor, and a next cursor which is empty at the last page.
+func (g *gitea) ListStarredPageContext(ctx context.Context, cursor string) ([]*Starred, string, error) {
+	page := pageOf(cursor)
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("limit", strconv.Itoa(giteaPerPage))
+
+	var repos []*giteaRepository
+	if err := retryPage(ctx, func() error {
+		repos = nil
+		_, err := g.getJSON(ctx, "/user/starred", params, &repos)
+		return err
+	}); err != nil {
+		return nil, "", fmt.Errorf("[err] ListStarredPage %w", err)
+	}
+
+	var starred []*Starred
+	for _, repo := range repos {
+		starred = append(starred, repo.toStarred())
+	}
+	if len(repos) < giteaPerPage {
+		return starred, "", nil
+	}
+	return starred, cursorOf(page + 1), nil
+}
+
+// ListStarredPagesContext returns starred repositories of n pages from a cursor, which are fetched concurrently.
+func (g *gitea) ListStarredPagesContext(ctx context.Context, cursor string, n int) ([]*Starred, string, error) {
+	return listStarredPages(ctx, cursor, n, g.ListStarredPageContext)
+}
+
+// CountStarredContext returns a count of starred repositories by X-Total-Count.
+func (g *gitea) CountStarredContext(ctx context.Context) (int, error) {
+	params := url.Values{}
+	params.Set("limit", "1")
+
+	var repos []*giteaRepository
+	header, err := g.getJSON(ctx, "/user/starred", params, &repos)
+	if err != nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", err)
+	}
+	total, err := strconv.Atoi(header.Get("X-Total-Count"))
+	if err != nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", ErrNotFound)
+	}
+	return total, nil
+}
+
+// SetReadmeContext sets readme to starred.
+func (g *gitea) SetReadmeContext(ctx context.Context, starred []*Starred) {
+	forEachParallel(len(starred), func(i int) {
+		s := starred[i]
+		content, err := g.getReadme(ctx, s.Owner, s.Repo)
+		if err != nil {
+			s.Error = fmt.Errorf("[err] SetReadme %w", err)
+			return
+		}
+		s.Readme = content
+	})
+}
+
+// ListReadmeContext returns readme list.
+func (g *gitea) ListReadmeContext(ctx context.Context, owners []string, repos []string) ([]*Readme, error) {
+	if len(owners) == 0 || len(repos) == 0 || len(owners) != len(repos) {
+		return nil, fmt.Errorf("[err] ListReadme %w", ErrInvalidParam)
+	}
+
+	readmeList := make([]*Readme, len(owners))
+	for i := range owners {
+		readmeList[i] = &Readme{Owner: owners[i], Repo: repos[i]}
+	}
+	forEachParallel(len(readmeList), func(i int) {
+		r := readmeList[i]
+		r.Content, r.Err = g.getReadme(ctx, r.Owner, r.Repo)
+	})
+	return readmeList, nil
+}
+
+// getReadme returns a raw readme of a repository in a default branch.
+func (g *gitea) getReadme(ctx context.Context, owner, repo string) (string, error) {
+	for _, p := range readmeCandidates {
+		body, _, err := g.get(ctx, fmt.Sprintf("/repos/%s/%s/raw/%s",
+			url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(p)), nil)
+		switch {
+		case err == nil:
+			return string(body), nil
+		case errors.Is(err, ErrNotFound):
+			continue
+		default:
+			return "", fmt.Errorf("[err] getReadme %w", err)
+		}
+	}
+	return "", fmt.Errorf("[err] getReadme %w", ErrNotFound)
+}
+
+// SetLanguagesContext sets a language breakdown to starred.
+func (g *gitea) SetLanguagesContext(ctx context.Context, starred []*Starred) error {
+	return setLanguages(ctx, starred, func(ctx context.Context, s *Starred) (map[string]int, error) {
+		var languages map[string]int
+		if _, err := g.getJSON(ctx, fmt.Sprintf("/repos/%s/%s/languages",
+			url.PathEscape(s.Owner), url.PathEscape(s.Repo)), nil, &languages); err != nil {
+			return nil, err
+		}
+		return languages, nil
+	})
+}
+
+func (r *giteaRepository) toStarred() *Starred {
+	return &Starred{
+		Provider:        ProviderGitea,
+		Owner:           r.Owner.Login,
+		Repo:            r.Name,
+		FullName:        r.FullName,
+		Url:             r.HTMLURL,
+		Description:     r.Description,
+		Topics:          r.Topics,
+		Language:        r.Language,
+		Homepage:        r.Website,
+		DefaultBranch:   r.DefaultBranch,
+		Archived:        r.Archived,
+		Fork:            r.Fork,
+		Template:        r.Template,
+		WatchersCount:   r.WatchersCount,
+		StargazersCount: r.StarsCount,
+		ForksCount:      r.ForksCount,
+		OpenIssuesCount: r.OpenIssues,
+		Size:            r.Size,
+		CreatedAt:       JsonTime{r.CreatedAt},
+		UpdateAt:        JsonTime{r.UpdatedAt},
+		PushedAt:        JsonTime{r.UpdatedAt},
+		CachedAt:        JsonTime{time.Now()},
+	}
+}
@@ -0,0 +1,150 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newGiteaServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gitea-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login":"allan","html_url":"https://gitea.example.com/allan","created":"2020-01-02T03:04:05Z"}`)
+	})
+	mux.HandleFunc("/api/v1/user/starred", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total-Count", strconv.Itoa(giteaPerPage+1))
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, "[")
+			for i := 0; i < giteaPerPage; i++ {
+				if i != 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"name":"repo%d","full_name":"allan/repo%d","owner":{"login":"allan"},"stars_count":%d,"topics":["go"]}`, i, i, i)
+			}
+			fmt.Fprint(w, "]")
+		case "2":
+			fmt.Fprint(w, `[{"name":"hello","full_name":"bob/hello","owner":{"login":"bob"},"description":"hello","html_url":"https://gitea.example.com/bob/hello",
+				"language":"Go","website":"https://hello.example.com","default_branch":"main","template":true,"open_issues_count":2,"size":64}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	})
+	mux.HandleFunc("/api/v1/repos/bob/hello/raw/README.md", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "# hello")
+	})
+	mux.HandleFunc("/api/v1/repos/allan/repo1/raw/README.rst", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "repo1")
+	})
+	mux.HandleFunc("/api/v1/repos/bob/hello/languages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Go":12000,"Makefile":300}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestNewGitea(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		baseURL string
+		token   string
+		host    string
+		isErr   bool
+	}{
+		"success":     {baseURL: "https://codeberg.org", token: "token", host: "codeberg.org"},
+		"empty url":   {token: "token", isErr: true},
+		"empty token": {baseURL: "https://codeberg.org", isErr: true},
+	}
+
+	for name, t := range tests {
+		g, err := NewGitea(t.baseURL, t.token)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(ProviderGitea, g.Provider(), name)
+			assert.Equal(t.host, g.Host(), name)
+		}
+	}
+}
+
+func TestGitea(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newGiteaServer()
+	defer server.Close()
+
+	g, err := NewGitea(server.URL, "gitea-token")
+	assert.NoError(err)
+	invalid, err := NewGitea(server.URL, "invalid-token")
+	assert.NoError(err)
+
+	user, err := g.UserContext(context.Background())
+	assert.NoError(err)
+	assert.Equal("allan", user.Owner)
+	assert.Equal(2020, user.CreatedAt.Year())
+	assert.True(user.UpdatedAt.IsZero())
+	_, err = invalid.UserContext(context.Background())
+	assert.Error(err)
+
+	starred, err := g.ListStarredAllContext(context.Background())
+	assert.NoError(err)
+	assert.Len(starred, giteaPerPage+1)
+	last := starred[len(starred)-1]
+	assert.Equal(ProviderGitea, last.Provider)
+	assert.Equal("bob", last.Owner)
+	assert.Equal("bob/hello", last.FullName)
+	assert.Equal("Go", last.Language)
+	assert.Equal("https://hello.example.com", last.Homepage)
+	assert.Equal("main", last.DefaultBranch)
+	assert.True(last.Template)
+	assert.Equal(2, last.OpenIssuesCount)
+	assert.Equal(64, last.Size)
+
+	page, next, err := g.ListStarredPageContext(context.Background(), "")
+	assert.NoError(err)
+	assert.Len(page, giteaPerPage)
+	assert.Equal("2", next)
+	page, next, err = g.ListStarredPageContext(context.Background(), next)
+	assert.NoError(err)
+	assert.Len(page, 1)
+	assert.Equal("", next)
+
+	// pages are fetched concurrently until the last page.
+	page, next, err = g.(PagesLister).ListStarredPagesContext(context.Background(), "", 5)
+	assert.NoError(err)
+	assert.Len(page, giteaPerPage+1)
+	assert.Equal("", next)
+	count, err := g.(StarredCounter).CountStarredContext(context.Background())
+	assert.NoError(err)
+	assert.Equal(giteaPerPage+1, count)
+
+	g.SetReadmeContext(context.Background(), []*Starred{last})
+	assert.NoError(last.Error)
+	assert.Equal("# hello", last.Readme)
+
+	assert.NoError(g.SetLanguagesContext(context.Background(), []*Starred{last}))
+	assert.Equal(map[string]int{"Go": 12000, "Makefile": 300}, last.Languages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(g.SetLanguagesContext(ctx, []*Starred{last}), context.Canceled)
+
+	readmeList, err := g.ListReadmeContext(context.Background(), []string{"allan", "allan"}, []string{"repo1", "repo2"})
+	assert.NoError(err)
+	assert.Equal("repo1", readmeList[0].Content)
+	assert.ErrorIs(readmeList[1].Err, ErrNotFound)
+
+	_, err = g.ListReadmeContext(context.Background(), []string{"allan"}, nil)
+	assert.Error(err)
+}
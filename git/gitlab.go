@@ -0,0 +1,282 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGitlabURL = "https://gitlab.com"
+)
+
+type gitlabUser struct {
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url"`
+	WebURL    string    `json:"web_url"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type gitlabProject struct {
+	ID                int       `json:"id"`
+	Path              string    `json:"path"`
+	PathWithNamespace string    `json:"path_with_namespace"`
+	WebURL            string    `json:"web_url"`
+	Description       string    `json:"description"`
+	Topics            []string  `json:"topics"`
+	TagList           []string  `json:"tag_list"`
+	StarCount         int       `json:"star_count"`
+	ForksCount        int       `json:"forks_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	LastActivityAt    time.Time `json:"last_activity_at"`
+	DefaultBranch     string    `json:"default_branch"`
+	ReadmeURL         string    `json:"readme_url"`
+	Archived          bool      `json:"archived"`
+	OpenIssuesCount   int       `json:"open_issues_count"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+	License *struct {
+		Key string `json:"key"`
+	} `json:"license"`
+	Statistics *struct {
+		RepositorySize int64 `json:"repository_size"`
+	} `json:"statistics"`
+	Namespace struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+}
+
+type gitlab struct {
+	*restClient
+	host string
+}
+
+// NewGitlab returns a gitlab client by a personal access token.
+// baseURL is the address of gitlab such as https://gitlab.com or a self-hosted one.
+// reference: https://docs.gitlab.com/ee/user/profile/personal_access_tokens.html
+func NewGitlab(baseURL, token string) (Git, error) {
+	if token == "" {
+		return nil, fmt.Errorf("[err] NewGitlab %w", ErrInvalidParam)
+	}
+	if baseURL == "" {
+		baseURL = defaultGitlabURL
+	}
+	host := hostOf(baseURL)
+	if host == "" {
+		return nil, fmt.Errorf("[err] NewGitlab %w", ErrInvalidParam)
+	}
+
+	client := newRestClient(strings.TrimRight(baseURL, "/")+"/api/v4", "PRIVATE-TOKEN", token)
+	return &gitlab{restClient: client, host: host}, nil
+}
+
+// Provider returns a name of provider.
+func (g *gitlab) Provider() string {
+	return ProviderGitlab
+}
+
+// Host returns a host of gitlab.
+func (g *gitlab) Host() string {
+	return g.host
+}
+
+// UserContext returns gitlab user object.
+func (g *gitlab) UserContext(ctx context.Context) (*User, error) {
+	var user *gitlabUser
+	if _, err := g.getJSON(ctx, "/user", nil, &user); err != nil {
+		return nil, fmt.Errorf("[err] User %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("[err] User %w", ErrNotFound)
+	}
+	return &User{
+		Owner:     user.Username,
+		AvatarURL: user.AvatarURL,
+		Url:       user.WebURL,
+		Bio:       user.Bio,
+		CreatedAt: JsonTime{user.CreatedAt},
+		CachedAt:  JsonTime{time.Now()},
+	}, nil
+}
+
+// ListStarredAllContext returns all of starred projects.
+func (g *gitlab) ListStarredAllContext(ctx context.Context) ([]*Starred, error) {
+	starred, err := listStarredByCursor(ctx, g.ListStarredPageContext)
+	if err != nil {
+		return starred, fmt.Errorf("[err] ListStarredAll %w", err)
+	}
+	return starred, nil
+}
+
+// ListStarredPageContext returns starred projects of a page from a cursor, and a next cursor which is empty at the last page.
+func (g *gitlab) ListStarredPageContext(ctx context.Context, cursor string) ([]*Starred, string, error) {
+	page := pageOf(cursor)
+	params := url.Values{}
+	params.Set("starred", "true")
+	params.Set("license", "true")
+	params.Set("statistics", "true")
+	params.Set("per_page", strconv.Itoa(perPage))
+	params.Set("page", strconv.Itoa(page))
+
+	var projects []*gitlabProject
+	var header http.Header
+	if err := retryPage(ctx, func() error {
+		var err error
+		projects = nil
+		header, err = g.getJSON(ctx, "/projects", params, &projects)
+		return err
+	}); err != nil {
+		return nil, "", fmt.Errorf("[err] ListStarredPage %w", err)
+	}
+
+	var starred []*Starred
+	for _, project := range projects {
+		starred = append(starred, project.toStarred())
+	}
+	return starred, header.Get("X-Next-Page"), nil
+}
+
+// ListStarredPagesContext returns starred projects of n pages from a cursor, which are fetched concurrently.
+func (g *gitlab) ListStarredPagesContext(ctx context.Context, cursor string, n int) ([]*Starred, string, error) {
+	return listStarredPages(ctx, cursor, n, g.ListStarredPageContext)
+}
+
+// CountStarredContext returns a count of starred projects by X-Total, which isn't given over 10,000 projects.
+func (g *gitlab) CountStarredContext(ctx context.Context) (int, error) {
+	params := url.Values{}
+	params.Set("starred", "true")
+	params.Set("simple", "true")
+	params.Set("per_page", "1")
+
+	var projects []*gitlabProject
+	header, err := g.getJSON(ctx, "/projects", params, &projects)
+	if err != nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", err)
+	}
+	total, err := strconv.Atoi(header.Get("X-Total"))
+	if err != nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", ErrNotFound)
+	}
+	return total, nil
+}
+
+// SetReadmeContext sets readme to starred.
+func (g *gitlab) SetReadmeContext(ctx context.Context, starred []*Starred) {
+	forEachParallel(len(starred), func(i int) {
+		s := starred[i]
+		content, err := g.getReadme(ctx, s.FullName, s.ReadmePath)
+		if err != nil {
+			s.Error = fmt.Errorf("[err] SetReadme %w", err)
+			return
+		}
+		s.Readme = content
+	})
+}
+
+// ListReadmeContext returns readme list.
+func (g *gitlab) ListReadmeContext(ctx context.Context, owners []string, repos []string) ([]*Readme, error) {
+	if len(owners) == 0 || len(repos) == 0 || len(owners) != len(repos) {
+		return nil, fmt.Errorf("[err] ListReadme %w", ErrInvalidParam)
+	}
+
+	readmeList := make([]*Readme, len(owners))
+	for i := range owners {
+		readmeList[i] = &Readme{Owner: owners[i], Repo: repos[i]}
+	}
+	forEachParallel(len(readmeList), func(i int) {
+		r := readmeList[i]
+		r.Content, r.Err = g.getReadme(ctx, r.Owner+"/"+r.Repo, "")
+	})
+	return readmeList, nil
+}
+
+// getReadme returns a raw readme of a project, a path is guessed if it's empty.
+func (g *gitlab) getReadme(ctx context.Context, fullName, path string) (string, error) {
+	paths := readmeCandidates
+	if path != "" {
+		paths = []string{path}
+	}
+
+	for _, p := range paths {
+		params := url.Values{}
+		params.Set("ref", "HEAD")
+		body, _, err := g.get(ctx, fmt.Sprintf("/projects/%s/repository/files/%s/raw",
+			url.PathEscape(fullName), url.PathEscape(p)), params)
+		switch {
+		case err == nil:
+			return string(body), nil
+		case errors.Is(err, ErrNotFound):
+			continue
+		default:
+			return "", fmt.Errorf("[err] getReadme %w", err)
+		}
+	}
+	return "", fmt.Errorf("[err] getReadme %w", ErrNotFound)
+}
+
+// SetLanguagesContext sets a language breakdown to starred, gitlab tells percentages which are kept as hundredths.
+func (g *gitlab) SetLanguagesContext(ctx context.Context, starred []*Starred) error {
+	return setLanguages(ctx, starred, func(ctx context.Context, s *Starred) (map[string]int, error) {
+		var percentages map[string]float64
+		if _, err := g.getJSON(ctx, fmt.Sprintf("/projects/%s/languages", url.PathEscape(s.FullName)), nil, &percentages); err != nil {
+			return nil, err
+		}
+		languages := make(map[string]int, len(percentages))
+		for name, percentage := range percentages {
+			languages[name] = int(percentage * 100)
+		}
+		return languages, nil
+	})
+}
+
+func (p *gitlabProject) toStarred() *Starred {
+	topics := p.Topics
+	if len(topics) == 0 {
+		topics = p.TagList
+	}
+	s := &Starred{
+		Provider:        ProviderGitlab,
+		Owner:           p.Namespace.FullPath,
+		Repo:            p.Path,
+		FullName:        p.PathWithNamespace,
+		Url:             p.WebURL,
+		Description:     p.Description,
+		Topics:          topics,
+		StargazersCount: p.StarCount,
+		ForksCount:      p.ForksCount,
+		OpenIssuesCount: p.OpenIssuesCount,
+		DefaultBranch:   p.DefaultBranch,
+		Archived:        p.Archived,
+		Fork:            p.ForkedFromProject != nil,
+		CreatedAt:       JsonTime{p.CreatedAt},
+		UpdateAt:        JsonTime{p.LastActivityAt},
+		PushedAt:        JsonTime{p.LastActivityAt},
+		CachedAt:        JsonTime{time.Now()},
+		ReadmePath:      gitlabReadmePath(p.ReadmeURL, p.DefaultBranch),
+	}
+	// a license and statistics are given only with license=true and statistics=true.
+	// a license is a key such as mit and apache-2.0, which is matched to SPDX id case-insensitively.
+	if p.License != nil {
+		s.License = p.License.Key
+	}
+	if p.Statistics != nil {
+		s.Size = int(p.Statistics.RepositorySize / 1024)
+	}
+	return s
+}
+
+// gitlabReadmePath extracts a file path from a readme url such as https://gitlab.com/owner/repo/-/blob/main/README.md.
+func gitlabReadmePath(readmeURL, branch string) string {
+	marker := "/-/blob/" + branch + "/"
+	if ix := strings.Index(readmeURL, marker); branch != "" && ix >= 0 {
+		return readmeURL[ix+len(marker):]
+	}
+	return ""
+}
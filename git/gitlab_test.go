@@ -0,0 +1,153 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newGitlabServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "gitlab-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"username":"allan","web_url":"https://gitlab.example.com/allan","created_at":"2020-01-02T03:04:05.000Z"}`)
+	})
+	mux.HandleFunc("/api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "2")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"id":1,"path":"hello","path_with_namespace":"group/hello","web_url":"https://gitlab.example.com/group/hello",
+				"description":"hello project","tag_list":["cli"],"star_count":10,"forks_count":2,"default_branch":"main",
+				"readme_url":"https://gitlab.example.com/group/hello/-/blob/main/docs/README.md",
+				"archived":true,"open_issues_count":3,"license":{"key":"mit"},"statistics":{"repository_size":2048},
+				"created_at":"2020-01-02T03:04:05.000Z","last_activity_at":"2021-01-02T03:04:05.000Z","namespace":{"full_path":"group"}}]`)
+		default:
+			fmt.Fprint(w, `[{"id":2,"path":"world","path_with_namespace":"group/sub/world","web_url":"https://gitlab.example.com/group/sub/world",
+				"topics":["go"],"star_count":1,"default_branch":"master","forked_from_project":{"id":1},"namespace":{"full_path":"group/sub"}}]`)
+		}
+	})
+	mux.HandleFunc("/api/v4/projects/group%2Fhello/repository/files/docs%2FREADME.md/raw", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "# hello")
+	})
+	mux.HandleFunc("/api/v4/projects/group%2Fsub%2Fworld/repository/files/README/raw", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "world")
+	})
+	mux.HandleFunc("/api/v4/projects/group%2Fhello/languages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Go":87.5,"Shell":12.5}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// match escaped paths of gitlab project ids.
+		r.URL.Path = r.URL.EscapedPath()
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func TestNewGitlab(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		baseURL string
+		token   string
+		host    string
+		isErr   bool
+	}{
+		"default":     {token: "token", host: "gitlab.com"},
+		"self-hosted": {baseURL: "https://gitlab.example.com/", token: "token", host: "gitlab.example.com"},
+		"empty token": {isErr: true},
+		"wrong url":   {baseURL: "gitlab", token: "token", isErr: true},
+	}
+
+	for name, t := range tests {
+		g, err := NewGitlab(t.baseURL, t.token)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(ProviderGitlab, g.Provider(), name)
+			assert.Equal(t.host, g.Host(), name)
+		}
+	}
+}
+
+func TestGitlab(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newGitlabServer()
+	defer server.Close()
+
+	g, err := NewGitlab(server.URL, "gitlab-token")
+	assert.NoError(err)
+	invalid, err := NewGitlab(server.URL, "invalid-token")
+	assert.NoError(err)
+
+	user, err := g.UserContext(context.Background())
+	assert.NoError(err)
+	assert.Equal("allan", user.Owner)
+	assert.Equal(2020, user.CreatedAt.Year())
+	assert.True(user.UpdatedAt.IsZero())
+	_, err = invalid.UserContext(context.Background())
+	assert.Error(err)
+
+	starred, err := g.ListStarredAllContext(context.Background())
+	assert.NoError(err)
+	assert.Len(starred, 2)
+	assert.Equal(ProviderGitlab, starred[0].Provider)
+	assert.Equal("group", starred[0].Owner)
+	assert.Equal("group/hello", starred[0].FullName)
+	assert.Equal([]string{"cli"}, starred[0].Topics)
+	assert.Equal(10, starred[0].StargazersCount)
+	assert.Equal("group/sub", starred[1].Owner)
+	assert.Equal([]string{"go"}, starred[1].Topics)
+	assert.Equal("docs/README.md", starred[0].ReadmePath)
+	assert.Equal("mit", starred[0].License)
+	assert.True(starred[0].Archived)
+	assert.Equal(3, starred[0].OpenIssuesCount)
+	assert.Equal(2, starred[0].Size)
+	assert.True(starred[1].Fork)
+	assert.Equal("master", starred[1].DefaultBranch)
+
+	page, next, err := g.ListStarredPageContext(context.Background(), "")
+	assert.NoError(err)
+	assert.Len(page, 1)
+	assert.Equal("2", next)
+	_, next, err = g.ListStarredPageContext(context.Background(), next)
+	assert.NoError(err)
+	assert.Equal("", next)
+
+	// pages are fetched concurrently until the last page.
+	page, next, err = g.(PagesLister).ListStarredPagesContext(context.Background(), "", 5)
+	assert.NoError(err)
+	assert.Len(page, 2)
+	assert.Equal("", next)
+	count, err := g.(StarredCounter).CountStarredContext(context.Background())
+	assert.NoError(err)
+	assert.Equal(2, count)
+
+	g.SetReadmeContext(context.Background(), starred)
+	assert.NoError(starred[0].Error)
+	assert.Equal("# hello", starred[0].Readme)
+	assert.NoError(starred[1].Error)
+	assert.Equal("world", starred[1].Readme)
+
+	// languages of a failed repository are left nil.
+	assert.NoError(g.SetLanguagesContext(context.Background(), starred))
+	assert.Equal(map[string]int{"Go": 8750, "Shell": 1250}, starred[0].Languages)
+	assert.Nil(starred[1].Languages)
+
+	readmeList, err := g.ListReadmeContext(context.Background(), []string{"group/sub", "group"}, []string{"world", "none"})
+	assert.NoError(err)
+	assert.Equal("world", readmeList[0].Content)
+	assert.ErrorIs(readmeList[1].Err, ErrNotFound)
+
+	_, err = g.ListReadmeContext(context.Background(), nil, nil)
+	assert.Error(err)
+}
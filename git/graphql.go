@@ -0,0 +1,471 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	github "github.com/google/go-github/v29/github"
+)
+
+const (
+	APIRest    = "rest"
+	APIGraphQL = "graphql"
+
+	// graphqlPerPage is the largest page of GraphQL.
+	graphqlPerPage = 100
+	// graphqlReadmeBatch is a count of repositories whose readme blobs are requested at once.
+	graphqlReadmeBatch = 50
+	// graphqlCursorPrefix marks a cursor of graphql, so that it isn't mistaken for a page of REST.
+	graphqlCursorPrefix = "gql:"
+)
+
+// graphqlReadmeFields are readme blobs of a repository, which are tried with each of readmeCandidates
+// and aliased as readme0, readme1 and so on.
+var graphqlReadmeFields = func() string {
+	var readme []string
+	for i, name := range readmeCandidates {
+		readme = append(readme, fmt.Sprintf(`readme%d: object(expression: "HEAD:%s") { ... on Blob { text } }`, i, name))
+	}
+	return strings.Join(readme, " ")
+}()
+
+// graphqlCountQuery counts starred repositories.
+const graphqlCountQuery = `query { viewer { starredRepositories { totalCount } } }`
+
+// graphqlStarredQuery fetches metadata of starred repositories in a page.
+// readme isn't fetched with pages, so that refreshing pages doesn't download readme of cached repositories.
+var graphqlStarredQuery = func() string {
+	return `query($first: Int!, $after: String) {
+  viewer {
+    starredRepositories(first: $first, after: $after, orderBy: {field: STARRED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      edges {
+        starredAt
+        node {
+          name nameWithOwner url description homepageUrl
+          isArchived isDisabled isFork isTemplate diskUsage
+          defaultBranchRef { name }
+          issues(states: OPEN) { totalCount }
+          owner { login }
+          repositoryTopics(first: 20) { nodes { topic { name } } }
+          primaryLanguage { name }
+          licenseInfo { spdxId }
+          stargazers { totalCount }
+          watchers { totalCount }
+          forkCount createdAt updatedAt pushedAt
+        }
+      }
+    }
+  }
+}`
+}()
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []*graphqlError `json:"errors"`
+}
+
+// graphqlError is an error in a response of GraphQL, such as a field which isn't in a schema of an old GitHub Enterprise.
+type graphqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *graphqlError) Error() string {
+	return fmt.Sprintf("[git][err] graphql %s %s", e.Type, e.Message)
+}
+
+type graphqlStarredData struct {
+	Viewer struct {
+		StarredRepositories struct {
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+			Edges []struct {
+				StarredAt time.Time          `json:"starredAt"`
+				Node      *graphqlRepository `json:"node"`
+			} `json:"edges"`
+		} `json:"starredRepositories"`
+	} `json:"viewer"`
+}
+
+// graphqlBlob is a readme blob, which is null if a path isn't found.
+type graphqlBlob struct {
+	Text string `json:"text"`
+}
+
+type graphqlRepository struct {
+	Name          string `json:"name"`
+	NameWithOwner string `json:"nameWithOwner"`
+	URL           string `json:"url"`
+	Description   string `json:"description"`
+	HomepageURL   string `json:"homepageUrl"`
+	IsArchived    bool   `json:"isArchived"`
+	IsDisabled    bool   `json:"isDisabled"`
+	IsFork        bool   `json:"isFork"`
+	IsTemplate    bool   `json:"isTemplate"`
+	DiskUsage     int    `json:"diskUsage"`
+	DefaultBranch *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	Issues struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"issues"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	LicenseInfo *struct {
+		SpdxID string `json:"spdxId"`
+	} `json:"licenseInfo"`
+	Stargazers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"stargazers"`
+	Watchers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"watchers"`
+	ForkCount int       `json:"forkCount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	PushedAt  time.Time `json:"pushedAt"`
+}
+
+// graphql fetches starred repositories with readme of github in bulk through GraphQL v4 API.
+// it falls back to REST API of the embedded wrapper when GraphQL isn't available.
+type graphql struct {
+	*wrapper
+	client   *github.Client
+	gqlSched *scheduler
+	endpoint string
+	fallback int32
+}
+
+// NewGithubGraphQL returns a github(enterprise) client fetching starred repositories with readme through GraphQL API.
+// baseURL is empty for github.com.
+func NewGithubGraphQL(baseURL, token string) (Git, error) {
+	rest, err := NewProviderGit(ProviderGithub, baseURL, token)
+	if err != nil {
+		return nil, fmt.Errorf("[err] NewGithubGraphQL %w", err)
+	}
+	w := rest.(*wrapper)
+
+	// GraphQL has another quota, so its requests are scheduled separately.
+	sched := newScheduler(nil)
+	client := github.NewClient(newOauth2Client(token, sched))
+	client.BaseURL = w.BaseURL
+
+	endpoint := w.BaseURL.String() + "graphql"
+	if strings.HasSuffix(w.BaseURL.Path, "/api/v3/") {
+		endpoint = strings.TrimSuffix(w.BaseURL.String(), "v3/") + "graphql"
+	}
+	return &graphql{wrapper: w, client: client, gqlSched: sched, endpoint: endpoint}, nil
+}
+
+// RateLimit returns a remaining quota of GraphQL API, or REST API after falling back.
+func (g *graphql) RateLimit() RateLimit {
+	if g.isFallback() {
+		return g.wrapper.RateLimit()
+	}
+	return g.gqlSched.RateLimit()
+}
+
+// ListStarredAllContext returns all of starred repositories.
+func (g *graphql) ListStarredAllContext(ctx context.Context) ([]*Starred, error) {
+	starred, err := listStarredByCursor(ctx, g.ListStarredPageContext)
+	if err != nil {
+		return starred, fmt.Errorf("[err] ListStarredAll %w", err)
+	}
+	return starred, nil
+}
+
+// ListStarredPageContext returns starred repositories of a page from a cursor, and a next cursor which is empty at the last page.
+func (g *graphql) ListStarredPageContext(ctx context.Context, cursor string) ([]*Starred, string, error) {
+	after := strings.TrimPrefix(cursor, graphqlCursorPrefix)
+	if g.isFallback() {
+		// a cursor of graphql can't be used by REST, so pages are fetched again from the first.
+		if after != cursor {
+			cursor = ""
+		}
+		return g.wrapper.ListStarredPageContext(ctx, cursor)
+	}
+	if cursor != "" && after == cursor {
+		// a page of REST is given, which was saved before a sync switched to graphql.
+		return g.wrapper.ListStarredPageContext(ctx, cursor)
+	}
+
+	starred, next, err := g.queryStarred(ctx, after)
+	switch {
+	case err == nil:
+		if next == "" {
+			return starred, "", nil
+		}
+		return starred, graphqlCursorPrefix + next, nil
+	case isQuotaExceeded(err):
+		return nil, "", fmt.Errorf("[err] ListStarredPage %w", ErrApiQuotaExceed)
+	case isUnavailable(err):
+		// a cursor of graphql can't be used by REST, so pages are fetched again from the first.
+		color.Yellow("[fallback][%s] graphql isn't available, so use rest api %s", g.Host(), err.Error())
+		atomic.StoreInt32(&g.fallback, 1)
+		return g.wrapper.ListStarredPageContext(ctx, "")
+	default:
+		// a transient error is already retried, so the page is fetched again on next sync.
+		return nil, "", fmt.Errorf("[err] ListStarredPage %w", err)
+	}
+}
+
+// ListStarredPagesContext returns starred repositories of pages from a cursor.
+// pages of GraphQL are linked by cursors, so a page is fetched at once unless it falls back to REST API.
+func (g *graphql) ListStarredPagesContext(ctx context.Context, cursor string, n int) ([]*Starred, string, error) {
+	after := strings.TrimPrefix(cursor, graphqlCursorPrefix)
+	if !g.isFallback() && (cursor == "" || after != cursor) {
+		return g.ListStarredPageContext(ctx, cursor)
+	}
+	if after != cursor {
+		// a cursor of graphql can't be used by REST, so pages are fetched again from the first.
+		cursor = ""
+	}
+	return g.wrapper.ListStarredPagesContext(ctx, cursor, n)
+}
+
+// CountStarredContext returns a count of starred repositories.
+func (g *graphql) CountStarredContext(ctx context.Context) (int, error) {
+	if g.isFallback() {
+		return g.wrapper.CountStarredContext(ctx)
+	}
+
+	var data *struct {
+		Viewer struct {
+			StarredRepositories struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"starredRepositories"`
+		} `json:"viewer"`
+	}
+	if err := g.query(ctx, graphqlCountQuery, nil, &data); err != nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", err)
+	}
+	if data == nil {
+		return 0, fmt.Errorf("[err] CountStarred %w", ErrNotFound)
+	}
+	return data.Viewer.StarredRepositories.TotalCount, nil
+}
+
+// SetReadmeContext sets readme to starred through GraphQL in batches,
+// and readme which isn't found by readmeCandidates is fetched through REST API.
+func (g *graphql) SetReadmeContext(ctx context.Context, starred []*Starred) {
+	if g.isFallback() {
+		g.wrapper.SetReadmeContext(ctx, starred)
+		return
+	}
+
+	var rest []*Starred
+	for start := 0; start < len(starred); start += graphqlReadmeBatch {
+		end := start + graphqlReadmeBatch
+		if end > len(starred) {
+			end = len(starred)
+		}
+		batch := starred[start:end]
+		if err := g.queryReadme(ctx, batch); err != nil {
+			switch {
+			case isQuotaExceeded(err):
+				setError(batch, fmt.Errorf("[err] SetReadme %w", ErrApiQuotaExceed))
+			case ctx.Err() != nil:
+				setError(batch, fmt.Errorf("[err] SetReadme %w", ctx.Err()))
+			case isUnavailable(err):
+				color.Yellow("[fallback][%s] graphql isn't available, so use rest api %s", g.Host(), err.Error())
+				atomic.StoreInt32(&g.fallback, 1)
+				rest = append(rest, batch...)
+			default:
+				setError(batch, fmt.Errorf("[err] SetReadme %w", err))
+			}
+			continue
+		}
+		for _, s := range batch {
+			if s.Readme == "" {
+				rest = append(rest, s)
+			}
+		}
+	}
+	g.wrapper.SetReadmeContext(ctx, rest)
+}
+
+// isUnavailable returns whether GraphQL can't be used, such as a disabled endpoint, a token without a permission and an old schema.
+func isUnavailable(err error) bool {
+	var gqlErr *graphqlError
+	var githubErr *github.ErrorResponse
+	switch {
+	case isQuotaExceeded(err):
+		return false
+	case errors.As(err, &gqlErr):
+		return true
+	case errors.As(err, &githubErr) && githubErr.Response != nil:
+		switch githubErr.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return true
+		}
+	}
+	return false
+}
+
+func setError(starred []*Starred, err error) {
+	for _, s := range starred {
+		s.Error = err
+	}
+}
+
+func (g *graphql) isFallback() bool {
+	return atomic.LoadInt32(&g.fallback) == 1
+}
+
+// query posts a GraphQL query, and decodes its data to v.
+// errors of repositories which aren't found are skipped, whose data is null.
+func (g *graphql) query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	var result *graphqlResponse
+	if err := retryPage(ctx, func() error {
+		return g.gqlSched.run(ctx, func(ctx context.Context) error {
+			req, err := g.client.NewRequest("POST", g.endpoint, &graphqlRequest{Query: query, Variables: variables})
+			if err != nil {
+				return err
+			}
+			result = nil
+			_, err = g.client.Do(ctx, req, &result)
+			return err
+		})
+	}); err != nil {
+		return err
+	}
+	if result == nil {
+		return ErrNotFound
+	}
+	for _, e := range result.Errors {
+		if e.Type == "RATE_LIMITED" {
+			return fmt.Errorf("%s %w", e.Message, ErrApiQuotaExceed)
+		}
+	}
+	for _, e := range result.Errors {
+		if e.Type != "NOT_FOUND" || len(result.Data) == 0 {
+			return e
+		}
+	}
+	return json.Unmarshal(result.Data, v)
+}
+
+// queryReadme requests readme blobs of starred at once, and sets readme which is found.
+func (g *graphql) queryReadme(ctx context.Context, starred []*Starred) error {
+	var params, fields []string
+	variables := map[string]interface{}{}
+	for i, s := range starred {
+		params = append(params, fmt.Sprintf("$owner%d: String!, $name%d: String!", i, i))
+		fields = append(fields, fmt.Sprintf("repo%d: repository(owner: $owner%d, name: $name%d) { %s }", i, i, i, graphqlReadmeFields))
+		variables[fmt.Sprintf("owner%d", i)] = s.Owner
+		variables[fmt.Sprintf("name%d", i)] = s.Repo
+	}
+	query := "query(" + strings.Join(params, ", ") + ") {\n  " + strings.Join(fields, "\n  ") + "\n}"
+
+	var data map[string]map[string]*graphqlBlob
+	if err := g.query(ctx, query, variables, &data); err != nil {
+		return fmt.Errorf("[err] queryReadme %w", err)
+	}
+	for i, s := range starred {
+		blobs := data[fmt.Sprintf("repo%d", i)]
+		for j := range readmeCandidates {
+			if blob := blobs[fmt.Sprintf("readme%d", j)]; blob != nil && blob.Text != "" {
+				s.Readme = blob.Text
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// queryStarred requests a page of starred repositories after a cursor.
+func (g *graphql) queryStarred(ctx context.Context, after string) ([]*Starred, string, error) {
+	variables := map[string]interface{}{"first": graphqlPerPage}
+	if after != "" {
+		variables["after"] = after
+	}
+
+	var data *graphqlStarredData
+	if err := g.query(ctx, graphqlStarredQuery, variables, &data); err != nil {
+		return nil, "", fmt.Errorf("[err] queryStarred %w", err)
+	}
+	if data == nil {
+		return nil, "", fmt.Errorf("[err] queryStarred %w", ErrNotFound)
+	}
+
+	connection := data.Viewer.StarredRepositories
+	var starred []*Starred
+	for _, edge := range connection.Edges {
+		if edge.Node == nil {
+			continue
+		}
+		starred = append(starred, edge.Node.toStarred(edge.StarredAt))
+	}
+
+	if !connection.PageInfo.HasNextPage {
+		return starred, "", nil
+	}
+	return starred, connection.PageInfo.EndCursor, nil
+}
+
+func (r *graphqlRepository) toStarred(starredAt time.Time) *Starred {
+	var topics []string
+	for _, node := range r.RepositoryTopics.Nodes {
+		topics = append(topics, node.Topic.Name)
+	}
+	s := &Starred{
+		Provider:        ProviderGithub,
+		Owner:           r.Owner.Login,
+		Repo:            r.Name,
+		FullName:        r.NameWithOwner,
+		Url:             r.URL,
+		Description:     r.Description,
+		Topics:          topics,
+		Homepage:        r.HomepageURL,
+		Archived:        r.IsArchived,
+		Disabled:        r.IsDisabled,
+		Fork:            r.IsFork,
+		Template:        r.IsTemplate,
+		WatchersCount:   r.Watchers.TotalCount,
+		StargazersCount: r.Stargazers.TotalCount,
+		ForksCount:      r.ForkCount,
+		OpenIssuesCount: r.Issues.TotalCount,
+		Size:            r.DiskUsage,
+		StarredAt:       JsonTime{starredAt},
+		CreatedAt:       JsonTime{r.CreatedAt},
+		UpdateAt:        JsonTime{r.UpdatedAt},
+		PushedAt:        JsonTime{r.PushedAt},
+		CachedAt:        JsonTime{time.Now()},
+	}
+	if r.PrimaryLanguage != nil {
+		s.Language = r.PrimaryLanguage.Name
+	}
+	if r.LicenseInfo != nil {
+		s.License = r.LicenseInfo.SpdxID
+	}
+	if r.DefaultBranch != nil {
+		s.DefaultBranch = r.DefaultBranch.Name
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	github "github.com/google/go-github/v29/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGraphQLServer(status int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		var req graphqlRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		switch {
+		case req.Query == graphqlCountQuery:
+			fmt.Fprint(w, `{"data":{"viewer":{"starredRepositories":{"totalCount":2}}}}`)
+		case strings.Contains(req.Query, "repository("):
+			// readme of bob/world isn't found by candidates, and a deleted repository is null.
+			fmt.Fprint(w, `{"data":{"repo0":{"readme0":null,"readme1":{"text":"hello readme"}},"repo1":{"readme0":null},"repo2":null},
+				"errors":[{"type":"NOT_FOUND","message":"Could not resolve to a Repository"}]}`)
+		case strings.Contains(req.Query, "object("):
+			w.WriteHeader(http.StatusBadRequest)
+		case req.Variables["after"] == nil:
+			fmt.Fprint(w, `{"data":{"viewer":{"starredRepositories":{"pageInfo":{"hasNextPage":true,"endCursor":"c1"},"edges":[
+				{"starredAt":"2021-01-02T03:04:05Z","node":{"name":"hello","nameWithOwner":"allan/hello","url":"https://github.example.com/allan/hello",
+				"owner":{"login":"allan"},"repositoryTopics":{"nodes":[{"topic":{"name":"go"}}]},"primaryLanguage":{"name":"Go"},
+				"licenseInfo":{"spdxId":"MIT"},"stargazers":{"totalCount":10},"watchers":{"totalCount":2},"forkCount":1,
+				"isArchived":true,"isFork":false,"diskUsage":128,"defaultBranchRef":{"name":"main"},"issues":{"totalCount":4},
+				"createdAt":"2020-01-02T03:04:05Z","updatedAt":"2020-01-02T03:04:05Z","pushedAt":"2020-01-02T03:04:05Z"}}]}}}}`)
+		default:
+			fmt.Fprint(w, `{"data":{"viewer":{"starredRepositories":{"pageInfo":{"hasNextPage":false,"endCursor":"c2"},"edges":[
+				{"starredAt":"2021-01-02T03:04:05Z","node":{"name":"world","nameWithOwner":"bob/world","owner":{"login":"bob"},
+				"primaryLanguage":null,"licenseInfo":null,"createdAt":"2020-01-02T03:04:05Z","updatedAt":"2020-01-02T03:04:05Z","pushedAt":"2020-01-02T03:04:05Z"}}]}}}}`)
+		}
+	})
+	mux.HandleFunc("/api/v3/user/starred", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"starred_at":"2021-01-02T03:04:05Z","repo":{"name":"rest","full_name":"allan/rest","owner":{"login":"allan"},"language":"Go","archived":true}}]`)
+	})
+	mux.HandleFunc("/api/v3/repos/bob/world/readme", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"file","encoding":"base64","content":"d29ybGQ="}`)
+	})
+	mux.HandleFunc("/api/v3/repos/bob/gone/readme", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGraphQL(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		status   int
+		names    []string
+		readme   []string
+		language []string
+		license  []string
+		archived []bool
+		isErr    bool
+	}{
+		"graphql": {status: http.StatusOK, names: []string{"allan/hello", "bob/world"},
+			readme: []string{"hello readme", "world"}, language: []string{"Go", ""}, license: []string{"MIT", ""}, archived: []bool{true, false}},
+		"fallback": {status: http.StatusNotFound, names: []string{"allan/rest"}, language: []string{"Go"}, license: []string{""}, archived: []bool{true}},
+		// an error which doesn't show graphql is unavailable doesn't fall back.
+		"bad request": {status: http.StatusBadRequest, isErr: true},
+	}
+
+	for name, t := range tests {
+		server := newGraphQLServer(t.status)
+		g, err := NewGithubGraphQL(server.URL, "token")
+		assert.NoError(err, name)
+		assert.Equal(ProviderGithub, g.Provider(), name)
+
+		starred, err := g.ListStarredAllContext(context.Background())
+		assert.Equal(t.isErr, err != nil, name)
+		assert.Equal(t.status == http.StatusNotFound, g.(*graphql).isFallback(), name)
+		count, err := g.(StarredCounter).CountStarredContext(context.Background())
+		assert.Equal(t.isErr, err != nil, name)
+		assert.Equal(len(t.names), count, name)
+		assert.Len(starred, len(t.names), name)
+		for i, s := range starred {
+			assert.Equal(t.names[i], s.FullName, name)
+			assert.Equal(t.language[i], s.Language, name)
+			assert.Equal(t.license[i], s.License, name)
+			assert.Equal(t.archived[i], s.Archived, name)
+		}
+		if t.status == http.StatusOK {
+			// a quota reserved by requests in flight is counted.
+			assert.InDelta(4990, g.RateLimit().Remaining, 2, name)
+			// pages don't carry readme, which is fetched in a batch and through REST if it isn't found by graphql.
+			for _, s := range starred {
+				assert.Empty(s.Readme, name)
+			}
+			g.SetReadmeContext(context.Background(), append(starred, &Starred{Owner: "bob", Repo: "gone", FullName: "bob/gone"}))
+			for i, s := range starred {
+				assert.NoError(s.Error, name)
+				assert.Equal(t.readme[i], s.Readme, name)
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	assert := assert.New(t)
+
+	response := func(status int) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: status}}
+	}
+	tests := map[string]struct {
+		err         error
+		unavailable bool
+	}{
+		"unauthorized": {err: response(http.StatusUnauthorized), unavailable: true},
+		"forbidden":    {err: response(http.StatusForbidden), unavailable: true},
+		"not found":    {err: fmt.Errorf("[err] queryStarred %w", response(http.StatusNotFound)), unavailable: true},
+		"schema":       {err: &graphqlError{Type: "undefinedField", Message: "Field 'diskUsage' doesn't exist"}, unavailable: true},
+		"quota":        {err: &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}},
+		"bad gateway":  {err: response(http.StatusBadGateway)},
+		"network":      {err: errors.New("connection reset by peer")},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.unavailable, isUnavailable(t.err), name)
+	}
+}
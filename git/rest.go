@@ -0,0 +1,232 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// restClient is a small json client for REST APIs of providers except Github.
+type restClient struct {
+	baseURL    string
+	authHeader string
+	authValue  string
+	sched      *scheduler
+	cond       *conditionalTransport
+	client     *http.Client
+}
+
+// newRestClient returns a restClient requesting to apiURL with an authorization header.
+func newRestClient(apiURL, authHeader, authValue string) *restClient {
+	cond := newConditionalTransport(nil)
+	sched := newScheduler(cond)
+	return &restClient{
+		baseURL:    strings.TrimRight(apiURL, "/"),
+		authHeader: authHeader,
+		authValue:  authValue,
+		sched:      sched,
+		cond:       cond,
+		client:     &http.Client{Transport: sched},
+	}
+}
+
+// RateLimit returns a remaining api quota.
+func (c *restClient) RateLimit() RateLimit {
+	return c.sched.RateLimit()
+}
+
+// SetCache sets a cache of conditional requests.
+func (c *restClient) SetCache(cache Cache) {
+	c.cond.cache = cache
+}
+
+// get requests GET to path and returns a body and headers, a request waits for a rate limit.
+func (c *restClient) get(ctx context.Context, path string, params url.Values) ([]byte, http.Header, error) {
+	u := c.baseURL + path
+	if len(params) != 0 {
+		u += "?" + params.Encode()
+	}
+
+	var body []byte
+	var header http.Header
+	err := c.sched.run(ctx, func(ctx context.Context) error {
+		var err error
+		body, header, err = c.do(ctx, u)
+		return err
+	})
+	return body, header, err
+}
+
+// do requests GET to u once.
+func (c *restClient) do(ctx context.Context, u string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set(c.authHeader, c.authValue)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, resp.Header, ErrApiQuotaExceed
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, resp.Header, ErrNotFound
+	case resp.StatusCode >= http.StatusBadRequest:
+		return nil, resp.Header, &statusError{url: u, status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	return body, resp.Header, nil
+}
+
+// getJSON requests GET to path and decodes a json body to out.
+func (c *restClient) getJSON(ctx context.Context, path string, params url.Values, out interface{}) (http.Header, error) {
+	body, header, err := c.get(ctx, path, params)
+	if err != nil {
+		return header, err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+// pageOf returns a page number of a cursor of REST APIs, an empty cursor is the first page.
+func pageOf(cursor string) int {
+	page, err := strconv.Atoi(cursor)
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// cursorOf returns a cursor of a page number, which is empty if there isn't a next page.
+func cursorOf(page int) string {
+	if page < 1 {
+		return ""
+	}
+	return strconv.Itoa(page)
+}
+
+// hostOf returns a host of url.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// listStarredByCursor fetches pages by listPage from the first page while a next cursor is given.
+// starred of succeeded pages are returned with PartialError, if a page fails after retries.
+func listStarredByCursor(ctx context.Context, listPage func(ctx context.Context, cursor string) ([]*Starred, string, error)) ([]*Starred, error) {
+	var starred []*Starred
+	for cursor := ""; ; {
+		list, next, err := listPage(ctx, cursor)
+		if err != nil {
+			if len(starred) == 0 || ctx.Err() != nil {
+				return nil, err
+			}
+			return starred, &PartialError{Pages: []*PageError{{Cursor: cursor, Err: err}}}
+		}
+		starred = append(starred, list...)
+		if next == "" {
+			return starred, nil
+		}
+		cursor = next
+	}
+}
+
+// listStarredPages fetches n pages from a numbered cursor concurrently, whose requests are limited by a scheduler.
+// it returns starred of pages until the last page and a next cursor, or an error if any of them failed.
+func listStarredPages(ctx context.Context, cursor string, n int, listPage func(ctx context.Context, cursor string) ([]*Starred, string, error)) ([]*Starred, string, error) {
+	if n < 1 {
+		n = 1
+	}
+	first := pageOf(cursor)
+	lists := make([][]*Starred, n)
+	nexts := make([]string, n)
+	errs := make([]error, n)
+	forEachParallel(n, func(i int) {
+		lists[i], nexts[i], errs[i] = listPage(ctx, cursorOf(first+i))
+	})
+
+	// pages after the last page are ignored.
+	var starred []*Starred
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			return nil, "", errs[i]
+		}
+		starred = append(starred, lists[i]...)
+		if nexts[i] == "" {
+			return starred, "", nil
+		}
+	}
+	return starred, nexts[n-1], nil
+}
+
+// setLanguages sets languages fetched by get to each of starred in parallel.
+// a failure of a repository is ignored, except a quota and a cancellation which are returned.
+func setLanguages(ctx context.Context, starred []*Starred, get func(ctx context.Context, s *Starred) (map[string]int, error)) error {
+	var mu sync.Mutex
+	var stopped error
+	forEachParallel(len(starred), func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		languages, err := get(ctx, starred[i])
+		switch {
+		case err == nil:
+			starred[i].Languages = languages
+		case isQuotaExceeded(err) || ctx.Err() != nil:
+			mu.Lock()
+			if stopped == nil {
+				stopped = err
+			}
+			mu.Unlock()
+		}
+	})
+	if ctx.Err() != nil {
+		return fmt.Errorf("[err] SetLanguages %w", ctx.Err())
+	}
+	if stopped != nil {
+		return fmt.Errorf("[err] SetLanguages %s %w", stopped.Error(), ErrApiQuotaExceed)
+	}
+	return nil
+}
+
+// forEachParallel calls fn for each index less than total with parallelSize goroutines.
+func forEachParallel(total int, fn func(i int)) {
+	queue := make(chan int, total)
+	for i := 0; i < total; i++ {
+		queue <- i
+	}
+	close(queue)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < parallelSize; i++ {
+		wg.Add(1)
+		go func() {
+			for ix := range queue {
+				fn(ix)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
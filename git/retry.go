@@ -0,0 +1,135 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	github "github.com/google/go-github/v29/github"
+)
+
+const (
+	// maxPageRetry is a count of retries of a page which failed by a transient error.
+	maxPageRetry = 3
+	// minBackoff is a backoff before the first retry, which doubles on each retry.
+	minBackoff = 500 * time.Millisecond
+	// maxBackoff is the longest backoff between retries.
+	maxBackoff = 8 * time.Second
+)
+
+// PageError is a page of starred which failed after retries.
+type PageError struct {
+	Cursor string `json:"cursor"` // an empty cursor is the first page.
+	Err    error  `json:"-"`
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("page %q %s", e.Cursor, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// RepoError is a repository whose readme failed by a transient error.
+type RepoError struct {
+	FullName string `json:"full_name"`
+	Err      error  `json:"-"`
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("repository %s %s", e.FullName, e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned with what succeeded, when some pages or repositories failed after retries.
+// pages after a failed page aren't fetched by providers paging with a cursor such as gitlab, gitea and GraphQL.
+type PartialError struct {
+	Pages []*PageError
+	Repos []*RepoError
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("[err] %d pages and %d repositories failed, %s", len(e.Pages), len(e.Repos), e.Unwrap())
+}
+
+// Unwrap returns the first failure, so that errors.Is finds ErrApiQuotaExceed.
+func (e *PartialError) Unwrap() error {
+	if len(e.Pages) != 0 {
+		return e.Pages[0]
+	}
+	if len(e.Repos) != 0 {
+		return e.Repos[0]
+	}
+	return nil
+}
+
+// statusError is a response of an error status from REST APIs of providers except Github.
+type statusError struct {
+	url    string
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %d %s", e.url, e.status, e.body)
+}
+
+// retryPage calls fn, and retries it with an exponential backoff and a jitter while it fails by a transient error.
+func retryPage(ctx context.Context, fn func() error) error {
+	for retry := 0; ; retry++ {
+		err := fn()
+		if err == nil || retry >= maxPageRetry || !IsTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a wait before a retry, which is a random duration between the half and the whole of an exponential backoff.
+func backoff(retry int) time.Duration {
+	d := minBackoff << uint(retry)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// IsTransient returns whether an error may succeed by a retry, such as a network error, a timeout and 5xx.
+// rate limits aren't retried here, because the scheduler waits for them.
+func IsTransient(err error) bool {
+	switch {
+	case err == nil, isQuotaExceeded(err), errors.Is(err, context.Canceled),
+		errors.Is(err, ErrNotFound), errors.Is(err, ErrInvalidParam):
+		return false
+	}
+
+	status := 0
+	var restErr *statusError
+	var githubErr *github.ErrorResponse
+	var gqlErr *graphqlError
+	switch {
+	case errors.As(err, &gqlErr):
+		return false
+	case errors.As(err, &restErr):
+		status = restErr.status
+	case errors.As(err, &githubErr) && githubErr.Response != nil:
+		status = githubErr.Response.StatusCode
+	default:
+		return true
+	}
+	return status >= http.StatusInternalServerError || status == http.StatusRequestTimeout
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		err       error
+		transient bool
+	}{
+		"nil":         {err: nil},
+		"quota":       {err: fmt.Errorf("[err] page %w", ErrApiQuotaExceed)},
+		"not found":   {err: ErrNotFound},
+		"canceled":    {err: context.Canceled},
+		"bad request": {err: &statusError{status: http.StatusBadRequest}},
+		"bad gateway": {err: &statusError{status: http.StatusBadGateway}, transient: true},
+		"timeout":     {err: context.DeadlineExceeded, transient: true},
+		"network":     {err: errors.New("connection reset by peer"), transient: true},
+		"graphql":     {err: &graphqlError{Type: "undefinedField"}},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.transient, IsTransient(t.err), name)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	for retry := 0; retry < 10; retry++ {
+		d := minBackoff << uint(retry)
+		if d > maxBackoff {
+			d = maxBackoff
+		}
+		wait := backoff(retry)
+		assert.True(wait >= d/2 && wait <= d, retry)
+	}
+}
+
+func TestListStarredAll_Partial(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		failures int32
+		names    []string
+		failed   []string
+	}{
+		"retried":        {failures: 1, names: []string{"group/hello", "group/world"}},
+		"partial result": {failures: maxPageRetry + 1, names: []string{"group/hello"}, failed: []string{"2"}},
+	}
+
+	for name, t := range tests {
+		var failures int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"id":1,"path":"hello","path_with_namespace":"group/hello"}]`)
+				return
+			}
+			if atomic.AddInt32(&failures, 1) <= t.failures {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `[{"id":2,"path":"world","path_with_namespace":"group/world"}]`)
+		}))
+
+		g, err := NewGitlab(server.URL, "gitlab-token")
+		assert.NoError(err, name)
+		started := time.Now()
+		starred, err := g.ListStarredAllContext(context.Background())
+		assert.True(time.Since(started) >= minBackoff/2, name)
+
+		var names []string
+		for _, s := range starred {
+			names = append(names, s.FullName)
+		}
+		assert.Equal(t.names, names, name)
+
+		var partial *PartialError
+		assert.Equal(len(t.failed) != 0, errors.As(err, &partial), name)
+		if partial != nil {
+			var failed []string
+			for _, page := range partial.Pages {
+				failed = append(failed, page.Cursor)
+			}
+			assert.Equal(t.failed, failed, name)
+		}
+		server.Close()
+	}
+}
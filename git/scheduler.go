@@ -0,0 +1,254 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitWait is the longest time which a request waits for a rate limit, a longer one fails at once.
+	maxRateLimitWait = 2 * time.Minute
+	// secondaryLimitWait is a time to wait when a secondary rate limit doesn't tell Retry-After.
+	secondaryLimitWait = time.Minute
+	// lowWaterRemaining is a remaining quota from which requests are paced until a reset.
+	lowWaterRemaining = 100
+	// maxPaceInterval is the longest interval between paced requests.
+	maxPaceInterval = time.Second
+	// maxRateLimitRetry is a count of retries of a request rejected by a rate limit.
+	maxRateLimitRetry = 3
+)
+
+// RateLimit is a remaining api quota of a provider, Limit is zero if a provider doesn't tell it.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// rateLimitError is returned when a request is rejected, or would be rejected, by a rate limit.
+type rateLimitError struct {
+	wait      time.Duration
+	secondary bool
+}
+
+func (e *rateLimitError) Error() string {
+	if e.secondary {
+		return fmt.Sprintf("%s secondary rate limit, retry after %s", ErrApiQuotaExceed, e.wait.Round(time.Second))
+	}
+	return fmt.Sprintf("%s reset after %s", ErrApiQuotaExceed, e.wait.Round(time.Second))
+}
+
+func (e *rateLimitError) Unwrap() error {
+	return ErrApiQuotaExceed
+}
+
+// scheduler is shared by all requests of a client, and it holds requests back before a quota is exhausted.
+// it's an http.RoundTripper which reads a rate limit from responses.
+type scheduler struct {
+	base http.RoundTripper
+
+	mu          sync.Mutex
+	limit       RateLimit
+	pausedUntil time.Time
+	next        time.Time
+}
+
+// newScheduler returns a scheduler sending requests with base, http.DefaultTransport is used if it's nil.
+func newScheduler(base http.RoundTripper) *scheduler {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &scheduler{base: base}
+}
+
+// RateLimit returns a remaining quota which is read from the latest responses.
+func (s *scheduler) RateLimit() RateLimit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// run calls fn with a timeout context when a quota allows, and retries it if it's rejected by a rate limit.
+func (s *scheduler) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	for retry := 0; ; retry++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+		subctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		err := fn(subctx)
+		cancel()
+
+		var limitErr *rateLimitError
+		if err == nil || !errors.As(err, &limitErr) || retry >= maxRateLimitRetry || limitErr.wait > maxRateLimitWait {
+			return err
+		}
+	}
+}
+
+// wait blocks until a request is allowed by a rate limit.
+// it fails without waiting if the quota is reset later than maxRateLimitWait.
+func (s *scheduler) wait(ctx context.Context) error {
+	s.mu.Lock()
+	now := time.Now()
+	until := now
+	switch {
+	case now.Before(s.pausedUntil):
+		until = s.pausedUntil
+	case s.limit.Limit == 0 || !now.Before(s.limit.Reset):
+	case s.limit.Remaining <= 0:
+		until = s.limit.Reset
+	case s.limit.Remaining < lowWaterRemaining:
+		// spread the rest of a quota until a reset.
+		interval := s.limit.Reset.Sub(now) / time.Duration(s.limit.Remaining)
+		if interval > maxPaceInterval {
+			interval = maxPaceInterval
+		}
+		if s.next.After(until) {
+			until = s.next
+		}
+		s.next = until.Add(interval)
+	}
+	delay := until.Sub(now)
+	if delay > maxRateLimitWait {
+		s.mu.Unlock()
+		return &rateLimitError{wait: delay}
+	}
+	// reserve a quota, so that concurrent requests don't go over it before responses arrive.
+	if s.limit.Limit != 0 && s.limit.Remaining > 0 {
+		s.limit.Remaining--
+	}
+	s.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RoundTrip sends a request, and returns rateLimitError if a response is rejected by a rate limit.
+func (s *scheduler) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := s.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if limitErr := s.observe(resp); limitErr != nil {
+		resp.Body.Close()
+		return nil, limitErr
+	}
+	return resp, nil
+}
+
+// observe updates a rate limit from a response, and returns rateLimitError if the response is rejected.
+func (s *scheduler) observe(resp *http.Response) *rateLimitError {
+	now := time.Now()
+	limit, ok := parseRateLimit(resp.Header)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		// responses of concurrent requests arrive in any order, a lower remaining wins in the same window.
+		if limit.Reset.After(s.limit.Reset) || limit.Remaining < s.limit.Remaining || s.limit.Limit == 0 {
+			s.limit = limit
+		}
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	// Retry-After is told by secondary rate limits of github and rate limits of gitlab.
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		s.pause(now.Add(wait))
+		return &rateLimitError{wait: wait, secondary: true}
+	}
+	// a primary rate limit is exhausted.
+	if ok && limit.Remaining == 0 {
+		s.pause(limit.Reset)
+		return &rateLimitError{wait: limit.Reset.Sub(now)}
+	}
+	// a secondary rate limit without Retry-After, or 429 without any hint.
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryLimit(resp) {
+		s.pause(now.Add(secondaryLimitWait))
+		return &rateLimitError{wait: secondaryLimitWait, secondary: true}
+	}
+	return nil
+}
+
+// pause holds requests back until t.
+func (s *scheduler) pause(t time.Time) {
+	if t.After(s.pausedUntil) {
+		s.pausedUntil = t
+	}
+}
+
+// isSecondaryLimit returns whether a forbidden response is a secondary rate limit of github.
+// a body is read and restored.
+func isSecondaryLimit(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	msg := strings.ToLower(string(body))
+	return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse detection")
+}
+
+// parseRateLimit reads a rate limit from headers of github(X-RateLimit-*), gitea(X-RateLimit-*) and gitlab(RateLimit-*).
+func parseRateLimit(header http.Header) (RateLimit, bool) {
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(header.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+		limit, _ := strconv.Atoi(header.Get(prefix + "Limit"))
+		reset, err := strconv.ParseInt(header.Get(prefix+"Reset"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if limit == 0 {
+			limit = remaining
+		}
+		return RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+	}
+	return RateLimit{}, false
+}
+
+// parseRetryAfter reads Retry-After which is seconds or a http date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if t.Before(now) {
+			return 0, true
+		}
+		return t.Sub(now), true
+	}
+	return 0, false
+}
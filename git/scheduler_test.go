@@ -0,0 +1,159 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Observe(t *testing.T) {
+	assert := assert.New(t)
+
+	reset := time.Now().Add(time.Hour).Unix()
+	tests := map[string]struct {
+		status    int
+		header    map[string]string
+		body      string
+		remaining int
+		limited   bool
+		secondary bool
+	}{
+		"ok": {status: http.StatusOK, header: map[string]string{
+			"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "4999", "X-RateLimit-Reset": strconv.FormatInt(reset, 10),
+		}, remaining: 4999},
+		"gitlab": {status: http.StatusOK, header: map[string]string{
+			"RateLimit-Limit": "2000", "RateLimit-Remaining": "1999", "RateLimit-Reset": strconv.FormatInt(reset, 10),
+		}, remaining: 1999},
+		"primary": {status: http.StatusForbidden, header: map[string]string{
+			"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset, 10),
+		}, limited: true},
+		"retry after": {status: http.StatusForbidden, header: map[string]string{"Retry-After": "30"}, limited: true, secondary: true},
+		"secondary":   {status: http.StatusForbidden, body: `{"message":"You have exceeded a secondary rate limit."}`, limited: true, secondary: true},
+		"too many":    {status: http.StatusTooManyRequests, limited: true, secondary: true},
+		"forbidden":   {status: http.StatusForbidden, body: `{"message":"Bad credentials"}`},
+	}
+
+	for name, t := range tests {
+		s := newScheduler(nil)
+		rec := httptest.NewRecorder()
+		for k, v := range t.header {
+			rec.Header().Set(k, v)
+		}
+		rec.WriteHeader(t.status)
+		rec.WriteString(t.body)
+
+		err := s.observe(rec.Result())
+		assert.Equal(t.limited, err != nil, name)
+		if err != nil {
+			assert.True(errors.Is(err, ErrApiQuotaExceed), name)
+			assert.Equal(t.secondary, err.secondary, name)
+			assert.True(s.pausedUntil.After(time.Now()), name)
+		} else {
+			assert.Equal(t.remaining, s.RateLimit().Remaining, name)
+		}
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "59")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	tests := map[string]struct {
+		calls     int32
+		remaining int
+	}{
+		"retry after secondary rate limit": {calls: 2, remaining: 59},
+	}
+
+	for name, t := range tests {
+		c := newRestClient(server.URL, "Authorization", "token fake")
+		started := time.Now()
+		body, _, err := c.get(context.Background(), "/", nil)
+		assert.NoError(err, name)
+		assert.Equal("[]", string(body), name)
+		assert.Equal(t.calls, atomic.LoadInt32(&calls), name)
+		assert.True(time.Since(started) >= time.Second, name)
+		assert.Equal(t.remaining, c.RateLimit().Remaining, name)
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		cancelAfter time.Duration
+		calls       int32
+	}{
+		"cancelled before a request": {cancelAfter: 0, calls: 0},
+		"cancelled while paused":     {cancelAfter: 100 * time.Millisecond, calls: 1},
+	}
+
+	for name, t := range tests {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusForbidden)
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if t.cancelAfter == 0 {
+			cancel()
+		} else {
+			time.AfterFunc(t.cancelAfter, cancel)
+		}
+		c := newRestClient(server.URL, "Authorization", "token fake")
+		started := time.Now()
+		_, _, err := c.get(ctx, "/", nil)
+		assert.True(errors.Is(err, context.Canceled), name)
+		assert.Equal(t.calls, atomic.LoadInt32(&calls), name)
+		assert.True(time.Since(started) < 10*time.Second, name)
+		cancel()
+		server.Close()
+	}
+}
+
+func TestScheduler_Wait(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		limit RateLimit
+		isErr bool
+	}{
+		"unknown":   {},
+		"plenty":    {limit: RateLimit{Limit: 5000, Remaining: 4000, Reset: time.Now().Add(time.Hour)}},
+		"low":       {limit: RateLimit{Limit: 5000, Remaining: 10, Reset: time.Now().Add(time.Second)}},
+		"exhausted": {limit: RateLimit{Limit: 5000, Remaining: 0, Reset: time.Now().Add(time.Hour)}, isErr: true},
+		"reset":     {limit: RateLimit{Limit: 5000, Remaining: 0, Reset: time.Now().Add(-time.Second)}},
+	}
+
+	for name, t := range tests {
+		s := newScheduler(nil)
+		s.limit = t.limit
+		err := s.wait(context.Background())
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			assert.True(errors.Is(err, ErrApiQuotaExceed), name)
+		}
+	}
+}
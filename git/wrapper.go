@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/fatih/color"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/fatih/color"
 	github "github.com/google/go-github/v29/github"
 )
 
@@ -26,22 +27,35 @@ type Readme struct {
 }
 
 type Starred struct {
-	Owner           string   `json:"owner,omitempty"`
-	Repo            string   `json:"repo,omitempty"`
-	FullName        string   `json:"full_name,omitempty"`
-	Url             string   `json:"url,omitempty"`
-	Description     string   `json:"description,omitempty"`
-	Topics          []string `json:"topics,omitempty"`
-	WatchersCount   int      `json:"watchers_count,omitempty"`
-	StargazersCount int      `json:"stargazers_count,omitempty"`
-	ForksCount      int      `json:"forks_count,omitempty"`
-	StarredAt       JsonTime `json:"starred_at,omitempty"`
-	CreatedAt       JsonTime `json:"created_at,omitempty"`
-	UpdateAt        JsonTime `json:"updated_at,omitempty"`
-	PushedAt        JsonTime `json:"pushed_at,omitempty"`
-	Readme          string   `json:"readme,omitempty"`
-	CachedAt        JsonTime `json:"cached_at,omitempty"`
-	Error           error    `json:"-"`
+	Provider        string         `json:"provider,omitempty"`
+	Owner           string         `json:"owner,omitempty"`
+	Repo            string         `json:"repo,omitempty"`
+	FullName        string         `json:"full_name,omitempty"`
+	Url             string         `json:"url,omitempty"`
+	Description     string         `json:"description,omitempty"`
+	Topics          []string       `json:"topics,omitempty"`
+	Language        string         `json:"language,omitempty"`
+	Languages       map[string]int `json:"languages"`         // bytes of each language, or relative sizes for gitlab. nil if it isn't fetched.
+	License         string         `json:"license,omitempty"` // SPDX id such as MIT.
+	Homepage        string         `json:"homepage,omitempty"`
+	DefaultBranch   string         `json:"default_branch,omitempty"`
+	Archived        bool           `json:"archived,omitempty"`
+	Disabled        bool           `json:"disabled,omitempty"`
+	Fork            bool           `json:"fork,omitempty"`
+	Template        bool           `json:"template,omitempty"`
+	WatchersCount   int            `json:"watchers_count,omitempty"`
+	StargazersCount int            `json:"stargazers_count,omitempty"`
+	ForksCount      int            `json:"forks_count,omitempty"`
+	OpenIssuesCount int            `json:"open_issues_count,omitempty"`
+	Size            int            `json:"size,omitempty"` // KB.
+	StarredAt       JsonTime       `json:"starred_at,omitempty"`
+	CreatedAt       JsonTime       `json:"created_at,omitempty"`
+	UpdateAt        JsonTime       `json:"updated_at,omitempty"`
+	PushedAt        JsonTime       `json:"pushed_at,omitempty"`
+	Readme          string         `json:"readme,omitempty"`
+	ReadmePath      string         `json:"readme_path,omitempty"`
+	CachedAt        JsonTime       `json:"cached_at,omitempty"`
+	Error           error          `json:"-"`
 }
 
 type User struct {
@@ -51,7 +65,6 @@ type User struct {
 	Bio       string   `json:"bio,omitempty"`
 	CreatedAt JsonTime `json:"created_at,omitempty"`
 	UpdatedAt JsonTime `json:"updated_at,omitempty"`
-	Token     string   `json:"token"`
 	CachedAt  JsonTime `json:"cached_at"`
 }
 
@@ -82,15 +95,39 @@ func (jt *JsonTime) UnmarshalJSON(bys []byte) error {
 
 type wrapper struct {
 	*github.Client
-	token string
+	host  string
+	sched *scheduler
+	cond  *conditionalTransport
 }
 
-// User returns github user object.
-func (w *wrapper) User() (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Provider returns a name of provider.
+func (w *wrapper) Provider() string {
+	return ProviderGithub
+}
+
+// Host returns a host of github.
+func (w *wrapper) Host() string {
+	return w.host
+}
+
+// RateLimit returns a remaining api quota.
+func (w *wrapper) RateLimit() RateLimit {
+	return w.sched.RateLimit()
+}
+
+// SetCache sets a cache of conditional requests, so that unchanged responses aren't charged against a rate limit.
+func (w *wrapper) SetCache(cache Cache) {
+	w.cond.cache = cache
+}
 
-	user, _, err := w.Users.Get(ctx, "")
+// UserContext returns github user object.
+func (w *wrapper) UserContext(ctx context.Context) (*User, error) {
+	var user *github.User
+	err := w.sched.run(ctx, func(ctx context.Context) error {
+		var err error
+		user, _, err = w.Users.Get(ctx, "")
+		return err
+	})
 	switch {
 	case err == nil:
 		if user == nil {
@@ -104,183 +141,129 @@ func (w *wrapper) User() (*User, error) {
 			CreatedAt: JsonTime{user.GetCreatedAt().Time},
 			UpdatedAt: JsonTime{user.GetUpdatedAt().Time},
 			CachedAt:  JsonTime{time.Now()},
-			Token:     w.token,
 		}, nil
-	case errors.As(err, &githubRateLimit):
+	case isQuotaExceeded(err):
 		return nil, fmt.Errorf("[err] NewGit %w", ErrApiQuotaExceed)
 	default:
 		return nil, fmt.Errorf("[err] NewGit %w", err)
 	}
 }
 
-// ListStarredAll returns all of starred projects.
-func (w *wrapper) ListStarredAll() ([]*Starred, error) {
+// ListStarredAllContext returns all of starred projects.
+func (w *wrapper) ListStarredAllContext(ctx context.Context) ([]*Starred, error) {
 	var repos []*github.StarredRepository
 	initPage := 1
-	lastPage := 1
 
 	// first requests
-	paging, resp, err := w.listStarredPaging(initPage, perPage)
+	paging, resp, err := w.listStarredPaging(ctx, initPage, perPage)
 	if err != nil {
-		if errors.As(err, &githubRateLimit) {
+		if isQuotaExceeded(err) {
 			return nil, fmt.Errorf("[err] ListStarredAll %w", ErrApiQuotaExceed)
 		}
 		return nil, fmt.Errorf("[err] ListStarredAll %w", err)
 	}
-	// getting last page.
-	lastPage = resp.LastPage
 	// append repos
 	repos = append(repos, paging...)
-	if initPage >= resp.LastPage {
-	} else {
+	if initPage < resp.LastPage {
 		lock := &sync.Mutex{}
-		wg := sync.WaitGroup{}
-		var multiQueue []chan int
-		var raisedErrors []error
-		for i := 0; i < parallelSize; i++ {
-			wg.Add(1)
-			ch := make(chan int, lastPage/parallelSize+parallelSize)
-			multiQueue = append(multiQueue, ch)
-			go func(queue chan int) {
-				for r := range queue {
-					paging, _, err := w.listStarredPaging(r, perPage)
-					if err != nil {
-						switch {
-						case errors.As(err, &githubRateLimit):
-							color.Red("[fail] getting github page %d %s", r, ErrApiQuotaExceed)
-						default:
-							color.Red("[fail] getting github page %d %s", r, err.Error())
-						}
-						raisedErrors = append(raisedErrors, err)
-						continue
-					}
-					// race condition.
-					lock.Lock()
-					repos = append(repos, paging...)
-					lock.Unlock()
+		partial := &PartialError{}
+		forEachParallel(resp.LastPage-initPage, func(i int) {
+			page := initPage + 1 + i
+			paging, _, err := w.listStarredPaging(ctx, page, perPage)
+
+			// race condition.
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if isQuotaExceeded(err) {
+					err = fmt.Errorf("%s %w", err.Error(), ErrApiQuotaExceed)
 				}
-				wg.Done()
-			}(ch)
-		}
-
-		// send requests
-		for i := initPage + 1; i <= lastPage; i++ {
-			hole := i % parallelSize
-			multiQueue[hole] <- i
-		}
-
-		// close channel
-		for _, ch := range multiQueue {
-			close(ch)
-		}
+				partial.Pages = append(partial.Pages, &PageError{Cursor: cursorOf(page), Err: err})
+				return
+			}
+			repos = append(repos, paging...)
+		})
 
-		wg.Wait()
-		if len(raisedErrors) != 0 {
-			return nil, fmt.Errorf("[err] ListStarredAll error count %d", len(raisedErrors))
+		// starred of succeeded pages are returned with failed pages.
+		if len(partial.Pages) != 0 {
+			sort.Slice(partial.Pages, func(i, j int) bool {
+				return pageOf(partial.Pages[i].Cursor) < pageOf(partial.Pages[j].Cursor)
+			})
+			color.Red("[fail] getting github pages %s, remaining %d", partial.Error(), w.RateLimit().Remaining)
+			return toGithubStarred(repos), fmt.Errorf("[err] ListStarredAll %w", partial)
 		}
 	}
 
-	var starred []*Starred
-	for _, star := range repos {
-		starred = append(starred, &Starred{
-			Owner: star.Repository.Owner.GetLogin(), Repo: star.Repository.GetName(),
-			FullName: star.Repository.GetFullName(), Url: star.Repository.GetHTMLURL(),
-			Description: star.GetRepository().GetDescription(), Topics: star.GetRepository().Topics,
-			WatchersCount: star.GetRepository().GetWatchersCount(), StargazersCount: star.GetRepository().GetStargazersCount(),
-			ForksCount: star.GetRepository().GetForksCount(), StarredAt: JsonTime{star.GetStarredAt().Time},
-			CreatedAt: JsonTime{star.GetRepository().GetCreatedAt().Time}, UpdateAt: JsonTime{star.GetRepository().GetUpdatedAt().Time},
-			PushedAt: JsonTime{star.GetRepository().GetPushedAt().Time}, CachedAt: JsonTime{time.Now()},
-		})
-	}
-
-	return starred, nil
+	return toGithubStarred(repos), nil
 }
 
-// SetReadme sets readme to starred.
-func (w *wrapper) SetReadme(starred []*Starred) {
-	if len(starred) == 0 {
-		return
+// ListStarredPageContext returns starred projects of a page from a cursor, and a next cursor which is empty at the last page.
+func (w *wrapper) ListStarredPageContext(ctx context.Context, cursor string) ([]*Starred, string, error) {
+	repos, resp, err := w.listStarredPaging(ctx, pageOf(cursor), perPage)
+	if err != nil {
+		if isQuotaExceeded(err) {
+			return nil, "", fmt.Errorf("[err] ListStarredPage %w", ErrApiQuotaExceed)
+		}
+		return nil, "", fmt.Errorf("[err] ListStarredPage %w", err)
 	}
+	return toGithubStarred(repos), cursorOf(resp.NextPage), nil
+}
 
-	total := len(starred)
-	queueSize := total/parallelSize + parallelSize
-
-	wg := sync.WaitGroup{}
-	var multiQueue []chan *Starred
+// ListStarredPagesContext returns starred repositories of n pages from a cursor, which are fetched concurrently.
+func (w *wrapper) ListStarredPagesContext(ctx context.Context, cursor string, n int) ([]*Starred, string, error) {
+	return listStarredPages(ctx, cursor, n, w.ListStarredPageContext)
+}
 
-	for i := 0; i < parallelSize; i++ {
-		wg.Add(1)
-		ch := make(chan *Starred, queueSize)
-		multiQueue = append(multiQueue, ch)
-		go func(queue chan *Starred) {
-			for r := range queue {
-				w.setReadmeToStarred(r)
-			}
-			wg.Done()
-		}(ch)
+// CountStarredContext returns a count of starred repositories, which is the last page of a repository per page.
+func (w *wrapper) CountStarredContext(ctx context.Context) (int, error) {
+	repos, resp, err := w.listStarredPaging(ctx, 1, 1)
+	if err != nil {
+		if isQuotaExceeded(err) {
+			return 0, fmt.Errorf("[err] CountStarred %w", ErrApiQuotaExceed)
+		}
+		return 0, fmt.Errorf("[err] CountStarred %w", err)
 	}
-
-	// distributes items
-	for i, star := range starred {
-		hole := i % parallelSize
-		multiQueue[hole] <- star
+	if resp.LastPage != 0 {
+		return resp.LastPage, nil
 	}
+	return len(repos), nil
+}
 
-	// close channel
-	for _, ch := range multiQueue {
-		close(ch)
-	}
-	wg.Wait()
+// SetReadmeContext sets readme to starred.
+func (w *wrapper) SetReadmeContext(ctx context.Context, starred []*Starred) {
+	forEachParallel(len(starred), func(i int) {
+		w.setReadmeToStarred(ctx, starred[i])
+	})
 }
 
-// ListReadme returns readme list.
-func (w *wrapper) ListReadme(owners []string, repos []string) ([]*Readme, error) {
+// ListReadmeContext returns readme list.
+func (w *wrapper) ListReadmeContext(ctx context.Context, owners []string, repos []string) ([]*Readme, error) {
 	if len(owners) == 0 || len(repos) == 0 || len(owners) != len(repos) {
 		return nil, fmt.Errorf("[err] GetMultiReadme %w", ErrInvalidParam)
 	}
 
-	total := len(owners)
-	queueSize := total/parallelSize + parallelSize
-
-	wg := sync.WaitGroup{}
-	var multiQueue []chan *Readme
-
-	for i := 0; i < parallelSize; i++ {
-		wg.Add(1)
-		ch := make(chan *Readme, queueSize)
-		multiQueue = append(multiQueue, ch)
-		go func(queue chan *Readme) {
-			for r := range queue {
-				r.Content, r.Err = w.getReadme(r.Owner, r.Repo)
-			}
-			wg.Done()
-		}(ch)
-	}
-
-	// distributes items
-	var readmeList []*Readme
-	for i, _ := range owners {
-		hole := i % parallelSize
-		readme := &Readme{Owner: owners[i], Repo: repos[i]}
-		readmeList = append(readmeList, readme)
-		multiQueue[hole] <- readme
+	readmeList := make([]*Readme, len(owners))
+	for i := range owners {
+		readmeList[i] = &Readme{Owner: owners[i], Repo: repos[i]}
 	}
-
-	// close channel
-	for _, ch := range multiQueue {
-		close(ch)
-	}
-	wg.Wait()
-
+	forEachParallel(len(readmeList), func(i int) {
+		r := readmeList[i]
+		r.Content, r.Err = w.getReadme(ctx, r.Owner, r.Repo)
+	})
 	return readmeList, nil
 }
 
-func (w *wrapper) getReadme(owner, repo string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	defer cancel()
-
-	readme, _, err := w.Repositories.GetReadme(ctx, owner, repo, nil)
+func (w *wrapper) getReadme(ctx context.Context, owner, repo string) (string, error) {
+	var readme *github.RepositoryContent
+	err := w.sched.run(ctx, func(ctx context.Context) error {
+		var err error
+		readme, _, err = w.Repositories.GetReadme(ctx, owner, repo, nil)
+		return err
+	})
 	if err != nil {
+		if isQuotaExceeded(err) {
+			return "", fmt.Errorf("[err] getReadme %s %w", err.Error(), ErrApiQuotaExceed)
+		}
 		return "", fmt.Errorf("[err] getReadme %w", err)
 	}
 
@@ -292,33 +275,71 @@ func (w *wrapper) getReadme(owner, repo string) (string, error) {
 	return content, nil
 }
 
-func (w *wrapper) setReadmeToStarred(s *Starred) {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	defer cancel()
-
-	readme, _, err := w.Repositories.GetReadme(ctx, s.Owner, s.Repo, nil)
-	if err != nil {
-		s.Error = fmt.Errorf("[err] setReadmeToStarred %w", err)
-		return
-	}
-
-	content, err := readme.GetContent()
+func (w *wrapper) setReadmeToStarred(ctx context.Context, s *Starred) {
+	content, err := w.getReadme(ctx, s.Owner, s.Repo)
 	if err != nil {
 		s.Error = fmt.Errorf("[err] setReadmeToStarred %w", err)
 		return
 	}
-
 	s.Readme = content
-	return
 }
 
-func (w *wrapper) listStarredPaging(page, perPage int) ([]*github.StarredRepository, *github.Response, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-	defer cancel()
-
+func (w *wrapper) listStarredPaging(ctx context.Context, page, perPage int) ([]*github.StarredRepository, *github.Response, error) {
 	opt := &github.ActivityListStarredOptions{}
 	opt.Page = page
 	opt.PerPage = perPage
 
-	return w.Activity.ListStarred(ctx, "", opt)
+	var repos []*github.StarredRepository
+	var resp *github.Response
+	err := retryPage(ctx, func() error {
+		return w.sched.run(ctx, func(ctx context.Context) error {
+			var err error
+			repos, resp, err = w.Activity.ListStarred(ctx, "", opt)
+			return err
+		})
+	})
+	return repos, resp, err
+}
+
+// SetLanguagesContext sets a language breakdown to starred, which costs a request per repository.
+// languages are optional, so it returns only an error of a quota or a cancellation, which stops fetching the rest.
+func (w *wrapper) SetLanguagesContext(ctx context.Context, starred []*Starred) error {
+	return setLanguages(ctx, starred, func(ctx context.Context, s *Starred) (map[string]int, error) {
+		var languages map[string]int
+		err := w.sched.run(ctx, func(ctx context.Context) error {
+			var err error
+			languages, _, err = w.Repositories.ListLanguages(ctx, s.Owner, s.Repo)
+			return err
+		})
+		return languages, err
+	})
+}
+
+func toGithubStarred(repos []*github.StarredRepository) []*Starred {
+	var starred []*Starred
+	for _, star := range repos {
+		repo := star.GetRepository()
+		starred = append(starred, &Starred{
+			Provider: ProviderGithub,
+			Owner:    repo.GetOwner().GetLogin(), Repo: repo.GetName(),
+			FullName: repo.GetFullName(), Url: repo.GetHTMLURL(),
+			Description: repo.GetDescription(), Topics: repo.Topics,
+			Language: repo.GetLanguage(), License: repo.GetLicense().GetSPDXID(),
+			Homepage: repo.GetHomepage(), DefaultBranch: repo.GetDefaultBranch(),
+			Archived: repo.GetArchived(), Disabled: repo.GetDisabled(), Fork: repo.GetFork(), Template: repo.GetIsTemplate(),
+			WatchersCount: repo.GetWatchersCount(), StargazersCount: repo.GetStargazersCount(),
+			ForksCount: repo.GetForksCount(), OpenIssuesCount: repo.GetOpenIssuesCount(), Size: repo.GetSize(),
+			StarredAt: JsonTime{star.GetStarredAt().Time},
+			CreatedAt: JsonTime{repo.GetCreatedAt().Time}, UpdateAt: JsonTime{repo.GetUpdatedAt().Time},
+			PushedAt: JsonTime{repo.GetPushedAt().Time}, CachedAt: JsonTime{time.Now()},
+		})
+	}
+	return starred
+}
+
+// isQuotaExceeded returns whether err is caused by a rate limit.
+// a target of errors.As is local, because it's called by concurrent requests.
+func isQuotaExceeded(err error) bool {
+	var rl *github.RateLimitError
+	return errors.Is(err, ErrApiQuotaExceed) || errors.As(err, &rl)
 }
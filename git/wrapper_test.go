@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -161,7 +162,7 @@ func TestWrapper_User(t *testing.T) {
 	}
 
 	for _, t := range tests {
-		_, err := t.input.User()
+		_, err := t.input.UserContext(context.Background())
 		assert.Equal(t.isErr, err != nil)
 	}
 }
@@ -179,7 +180,7 @@ func TestWrapper_ListStarredAll(t *testing.T) {
 	}
 
 	for _, t := range tests {
-		result, err := g.ListStarredAll()
+		result, err := g.ListStarredAllContext(context.Background())
 		assert.Equal(t.isErr, err != nil)
 		if err == nil {
 			total := map[string]string{}
@@ -199,7 +200,7 @@ func TestWrapper_ListReadme(t *testing.T) {
 	g, err := NewGit(os.Getenv("GITHUB_TOKEN"))
 	assert.NoError(err)
 
-	starred, err := g.ListStarredAll()
+	starred, err := g.ListStarredAllContext(context.Background())
 	assert.NoError(err)
 
 	var owners []string
@@ -220,7 +221,7 @@ func TestWrapper_ListReadme(t *testing.T) {
 	}
 
 	for _, t := range tests {
-		readmeList, err := g.ListReadme(t.owners, t.repos)
+		readmeList, err := g.ListReadmeContext(context.Background(), t.owners, t.repos)
 		assert.Equal(t.isErr, err != nil)
 		if err == nil {
 			assert.Len(readmeList, t.size)
@@ -244,7 +245,7 @@ func TestWrapper_SetReadme(t *testing.T) {
 	g, err := NewGit(os.Getenv("GITHUB_TOKEN"))
 	assert.NoError(err)
 
-	starred, err := g.ListStarredAll()
+	starred, err := g.ListStarredAllContext(context.Background())
 	assert.NoError(err)
 
 	tests := map[string]struct {
@@ -257,7 +258,7 @@ func TestWrapper_SetReadme(t *testing.T) {
 	}
 
 	for _, t := range tests {
-		g.SetReadme(t.starred)
+		g.SetReadmeContext(context.Background(), t.starred)
 		fail := 0
 		success := 0
 		for _, star := range t.starred {
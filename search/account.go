@@ -0,0 +1,252 @@
+package search
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/git"
+)
+
+const (
+	metaBucketName    = "meta"
+	accountBucketName = "account"
+	saltSize          = 32
+
+	// legacyAccountHost is a host of accounts of old versions, which only supported github.com.
+	legacyAccountHost = "github.com"
+)
+
+var (
+	saltKey = []byte("salt")
+
+	// legacyTokenPattern matches a classic token of github, and a token prefixed by its kind such as ghp_ and github_pat_.
+	legacyTokenPattern = regexp.MustCompile(`^([0-9a-f]{40}|gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{30,})$`)
+)
+
+// resolveAccount sets a key of an account identity(login@host) to a source.
+// a token is never persisted, an account is looked up by a salted hash of the token.
+func (s *searcher) resolveAccount(ctx context.Context, src *source) error {
+	salt, err := s.getSalt()
+	if err != nil {
+		return fmt.Errorf("[err] resolveAccount %w", err)
+	}
+	hash := tokenHash(salt, src.git.Host(), src.token)
+
+	// look up the account of a token.
+	var key string
+	s.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(accountBucketName)); bucket != nil {
+			key = string(bucket.Get([]byte(hash)))
+		}
+		return nil
+	})
+	if key != "" {
+		src.key = key
+		return nil
+	}
+
+	// ask an account to a provider.
+	user, err := src.git.UserContext(ctx)
+	if err != nil {
+		return fmt.Errorf("[err] resolveAccount %w", err)
+	}
+	key = accountKey(user.Owner, src.git.Host())
+	if err := s.putUser(key, user); err != nil {
+		return fmt.Errorf("[err] resolveAccount %w", err)
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(accountBucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(hash), []byte(key))
+	}); err != nil {
+		return fmt.Errorf("[err] resolveAccount %w", err)
+	}
+	src.key = key
+	return nil
+}
+
+// migrateLegacyAccounts scans users and starred buckets keyed by raw tokens of old versions, which runs once on start.
+// each of them is moved to an account identity of its user with a salted hash of the token, or dropped if its user isn't cached,
+// so that no raw token is left in db.
+func (s *searcher) migrateLegacyAccounts() error {
+	salt, err := s.getSalt()
+	if err != nil {
+		return fmt.Errorf("[err] migrateLegacyAccounts %w", err)
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		legacyKeys := map[string]bool{}
+		users := tx.Bucket([]byte(userBucketName))
+		if users != nil {
+			users.ForEach(func(k, v []byte) error {
+				if isLegacyAccountKey(string(k)) {
+					legacyKeys[string(k)] = true
+				}
+				return nil
+			})
+		}
+		tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if key := strings.TrimSuffix(string(name), "_"+starredBucketSuffix); key != string(name) && isLegacyAccountKey(key) {
+				legacyKeys[key] = true
+			}
+			return nil
+		})
+		if len(legacyKeys) == 0 {
+			return nil
+		}
+
+		accounts, err := tx.CreateBucketIfNotExists([]byte(accountBucketName))
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(legacyKeys))
+		for legacyKey := range legacyKeys {
+			keys = append(keys, legacyKey)
+		}
+		sort.Strings(keys)
+		for _, legacyKey := range keys {
+			var user *git.User
+			if users != nil {
+				if err := json.Unmarshal(users.Get([]byte(legacyKey)), &user); err != nil {
+					user = nil
+				}
+			}
+			if user == nil || user.Owner == "" {
+				if err := dropAccount(tx, legacyKey); err != nil {
+					return err
+				}
+				color.White("[drop] cached data of an unknown account")
+				continue
+			}
+			key := accountKey(user.Owner, legacyAccountHost)
+			if err := moveAccount(tx, legacyKey, key, user); err != nil {
+				return err
+			}
+			if err := accounts.Put([]byte(tokenHash(salt, legacyAccountHost, legacyKey)), []byte(key)); err != nil {
+				return err
+			}
+			color.White("[migrate] cached data of %s", key)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("[err] migrateLegacyAccounts %w", err)
+	}
+	return nil
+}
+
+// moveAccount moves a user and starred of a legacy key to a new key in a transaction, and deletes the legacy one.
+// a user and starred of the new key are kept, because they are newer than the legacy ones.
+func moveAccount(tx *bolt.Tx, legacyKey, key string, user *git.User) error {
+	userData, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	users, err := tx.CreateBucketIfNotExists([]byte(userBucketName))
+	if err != nil {
+		return err
+	}
+	if users.Get([]byte(key)) == nil {
+		if err := users.Put([]byte(key), userData); err != nil {
+			return err
+		}
+	}
+
+	legacy := tx.Bucket([]byte(starredBucketName(legacyKey)))
+	if legacy != nil {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(starredBucketName(key)))
+		if err != nil {
+			return err
+		}
+		if err := legacy.ForEach(func(k, v []byte) error {
+			if bucket.Get(k) != nil {
+				return nil
+			}
+			return bucket.Put(k, v)
+		}); err != nil {
+			return err
+		}
+	}
+	return dropAccount(tx, legacyKey)
+}
+
+// dropAccount deletes a user and starred of a key in a transaction.
+func dropAccount(tx *bolt.Tx, key string) error {
+	if users := tx.Bucket([]byte(userBucketName)); users != nil {
+		if err := users.Delete([]byte(key)); err != nil {
+			return err
+		}
+	}
+	if tx.Bucket([]byte(starredBucketName(key))) == nil {
+		return nil
+	}
+	return tx.DeleteBucket([]byte(starredBucketName(key)))
+}
+
+// putUser writes a user of an account key to db.
+func (s *searcher) putUser(key string, user *git.User) error {
+	userData, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("[err] putUser %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(userBucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), userData)
+	})
+}
+
+// getSalt returns a random salt of this machine, which is made at first.
+func (s *searcher) getSalt() ([]byte, error) {
+	var salt []byte
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(metaBucketName))
+		if err != nil {
+			return err
+		}
+		if saved := bucket.Get(saltKey); len(saved) != 0 {
+			salt = append([]byte{}, saved...)
+			return nil
+		}
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		return bucket.Put(saltKey, salt)
+	}); err != nil {
+		return nil, fmt.Errorf("[err] getSalt %w", err)
+	}
+	return salt, nil
+}
+
+// tokenHash returns a salted hash of a token in a host.
+func tokenHash(salt []byte, host, token string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(host))
+	h.Write([]byte{0})
+	h.Write([]byte(token))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// accountKey returns a stable identity of an account.
+func accountKey(login, host string) string {
+	return login + "@" + host
+}
+
+// isLegacyAccountKey returns whether a key is a raw token of github.com in old versions, not an account identity(login@host).
+// only keys which look like tokens are legacy, so that other buckets aren't dropped.
+func isLegacyAccountKey(key string) bool {
+	return legacyTokenPattern.MatchString(key)
+}
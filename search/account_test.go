@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearcher_ResolveAccount(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+
+	token := "ghp_" + strings.Repeat("a", 36)
+	otherToken := strings.Repeat("0123456789", 4)
+	orphanToken := "github_pat_" + strings.Repeat("o", 30)
+	g, err := git.NewGit(token)
+	assert.NoError(err)
+	s := &searcher{db: db}
+
+	// cached data of old versions which is keyed by a raw token.
+	legacyUser := []byte(`{"owner":"allan","token":"` + token + `"}`)
+	assert.NoError(db.Update(func(tx *bolt.Tx) error {
+		users, err := tx.CreateBucket([]byte(userBucketName))
+		if err != nil {
+			return err
+		}
+		if err := users.Put([]byte(token), legacyUser); err != nil {
+			return err
+		}
+		starred, err := tx.CreateBucket([]byte(starredBucketName(token)))
+		if err != nil {
+			return err
+		}
+		if err := starred.Put([]byte("allan/hello"), []byte(`{"full_name":"allan/hello"}`)); err != nil {
+			return err
+		}
+
+		// caches of other tokens, whose user is known or unknown.
+		if err := users.Put([]byte(otherToken), []byte(`{"owner":"bob","token":"`+otherToken+`"}`)); err != nil {
+			return err
+		}
+		other, err := tx.CreateBucket([]byte(starredBucketName(otherToken)))
+		if err != nil {
+			return err
+		}
+		if err := other.Put([]byte("bob/world"), []byte(`{"full_name":"bob/world"}`)); err != nil {
+			return err
+		}
+		orphan, err := tx.CreateBucket([]byte(starredBucketName(orphanToken)))
+		if err != nil {
+			return err
+		}
+		if err := orphan.Put([]byte("carol/cake"), []byte(`{"full_name":"carol/cake"}`)); err != nil {
+			return err
+		}
+
+		// keys which don't look like tokens aren't legacy.
+		notes, err := tx.CreateBucket([]byte(starredBucketName("notes")))
+		if err != nil {
+			return err
+		}
+		return notes.Put([]byte("dave/pie"), []byte(`{"full_name":"dave/pie"}`))
+	}))
+	assert.NoError(s.migrateLegacyAccounts())
+
+	tests := map[string]struct {
+		src    *source
+		output string
+	}{
+		"migrate": {src: &source{token: token, git: g}, output: "allan@github.com"},
+		"mapped":  {src: &source{token: token, git: g}, output: "allan@github.com"},
+	}
+
+	for _, name := range []string{"migrate", "mapped"} {
+		t := tests[name]
+		assert.NoError(s.resolveAccount(context.Background(), t.src), name)
+		assert.Equal(t.output, t.src.key, name)
+
+		db.View(func(tx *bolt.Tx) error {
+			assert.Nil(tx.Bucket([]byte(starredBucketName(token))), name)
+			assert.NotEmpty(tx.Bucket([]byte(starredBucketName(t.output))).Get([]byte("allan/hello")), name)
+
+			assert.NotEmpty(tx.Bucket([]byte(starredBucketName("bob@github.com"))).Get([]byte("bob/world")), name)
+			assert.NotEmpty(tx.Bucket([]byte(starredBucketName("notes"))).Get([]byte("dave/pie")), name)
+
+			users := tx.Bucket([]byte(userBucketName))
+			assert.Empty(users.Get([]byte(token)), name)
+			assert.NotEmpty(users.Get([]byte("bob@github.com")), name)
+			var user *git.User
+			assert.NoError(json.Unmarshal(users.Get([]byte(t.output)), &user), name)
+			assert.Equal("allan", user.Owner, name)
+
+			// a raw token is never persisted.
+			assert.NoError(tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+				for _, raw := range []string{token, otherToken, orphanToken} {
+					assert.False(strings.Contains(string(bucketName), raw), name)
+				}
+				return b.ForEach(func(k, v []byte) error {
+					for _, raw := range []string{token, otherToken, orphanToken} {
+						assert.False(strings.Contains(string(k), raw), name)
+						assert.False(strings.Contains(string(v), raw), name)
+					}
+					return nil
+				})
+			}), name)
+			return nil
+		})
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		salt  []byte
+		host  string
+		token string
+		same  bool
+	}{
+		"same":       {salt: []byte("salt"), host: "github.com", token: "token", same: true},
+		"other salt": {salt: []byte("other"), host: "github.com", token: "token"},
+		"other host": {salt: []byte("salt"), host: "gitlab.com", token: "token"},
+	}
+
+	base := tokenHash([]byte("salt"), "github.com", "token")
+	for name, t := range tests {
+		hash := tokenHash(t.salt, t.host, t.token)
+		assert.Equal(t.same, hash == base, name)
+		assert.NotContains(hash, t.token, name)
+	}
+}
+
+func TestIsLegacyAccountKey(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input  string
+		output bool
+	}{
+		"classic":  {input: strings.Repeat("0123456789", 4), output: true},
+		"prefixed": {input: "ghp_" + strings.Repeat("a", 36), output: true},
+		"fine":     {input: "github_pat_" + strings.Repeat("a_", 40), output: true},
+		"account":  {input: "allan@github.com"},
+		"other":    {input: "notes"},
+		"short":    {input: "ghp_token"},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, isLegacyAccountKey(t.input), name)
+	}
+}
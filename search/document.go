@@ -0,0 +1,71 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gjbae1212/findgs/git"
+)
+
+const (
+	// langWeight is a count of repeated language names for a language which takes up the whole repository.
+	langWeight = 10
+	// minLangShare is the least share of a language which is indexed.
+	minLangShare = 0.01
+)
+
+// document is starred which is indexed.
+// git.Starred is embedded by value, so that its fields are indexed without a prefix.
+// README is indexed as headings, prose and code blocks instead of raw markdown, which is kept in the db for display.
+type document struct {
+	git.Starred
+	Lang           []string `json:"lang"` // lowercase languages repeated by their shares, so that a main language scores higher.
+	ReadmeHeadings string   `json:"readme_headings"`
+	ReadmeProse    string   `json:"readme_prose"`
+	ReadmeCode     string   `json:"readme_code"`
+}
+
+// newDocument returns a document of starred.
+func newDocument(starred *git.Starred) *document {
+	readme := parseMarkdown(starred.Readme)
+	return &document{Starred: *starred, Lang: weightedLanguages(starred),
+		ReadmeHeadings: readme.Headings, ReadmeProse: readme.Prose, ReadmeCode: readme.Code}
+}
+
+// weightedLanguages returns lowercase names of languages which are repeated by their shares of bytes.
+// the primary language is used if a breakdown of languages isn't fetched.
+func weightedLanguages(starred *git.Starred) []string {
+	if len(starred.Languages) == 0 {
+		if starred.Language == "" {
+			return nil
+		}
+		return []string{strings.ToLower(starred.Language)}
+	}
+
+	total := 0
+	var names []string
+	for name, size := range starred.Languages {
+		if size > 0 {
+			total += size
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var langs []string
+	for _, name := range names {
+		share := float64(starred.Languages[name]) / float64(total)
+		if share < minLangShare {
+			continue
+		}
+		count := int(math.Round(share * langWeight))
+		if count < 1 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			langs = append(langs, strings.ToLower(name))
+		}
+	}
+	return langs
+}
@@ -0,0 +1,27 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedLanguages(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input  *git.Starred
+		output []string
+	}{
+		"none":      {input: &git.Starred{}, output: nil},
+		"primary":   {input: &git.Starred{Language: "Go"}, output: []string{"go"}},
+		"breakdown": {input: &git.Starred{Language: "Rust", Languages: map[string]int{"Rust": 7000, "C": 2500, "Shell": 500}}, output: []string{"c", "c", "c", "rust", "rust", "rust", "rust", "rust", "rust", "rust", "shell"}},
+		"minor":     {input: &git.Starred{Languages: map[string]int{"Go": 9950, "Makefile": 50}}, output: []string{"go", "go", "go", "go", "go", "go", "go", "go", "go", "go"}},
+		"empty":     {input: &git.Starred{Language: "Go", Languages: map[string]int{}}, output: []string{"go"}},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, weightedLanguages(t.input), name)
+	}
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/mapping"
+	bleve_search "github.com/blevesearch/bleve/search"
+)
+
+const (
+	// KindMatch is a contribution of a word which is analyzed such as cli.
+	KindMatch = "match"
+	// KindPhrase is a contribution of a quoted phrase such as "grpc gateway".
+	KindPhrase = "phrase"
+	// KindWildcard is a contribution of a term expanded from a wildcard such as hello*.
+	KindWildcard = "wildcard"
+	// KindFilter is a contribution of metadata such as stars:>1000 and archived:false.
+	KindFilter = "filter"
+)
+
+var (
+	// termWeight matches an explanation of a term, weight(field:term^boost in id) or fieldWeight(field:term in id).
+	termWeight = regexp.MustCompile(`^(?:weight|fieldWeight)\(([^:]+):(.*?)(?:\^[0-9.]+)? in .*\), product of:$`)
+	// coordFactor matches an explanation of a coordination factor such as coord(1/2).
+	coordFactor = regexp.MustCompile(`^coord\(\d+/\d+\)$`)
+)
+
+// Explanation is a tree of how a score of a hit is computed by bleve.
+type Explanation struct {
+	Value    float64        `json:"value"`
+	Message  string         `json:"message"`
+	Children []*Explanation `json:"children,omitempty"`
+}
+
+// Contribution is a part of a score, which a term matched in a field contributes to.
+type Contribution struct {
+	Query string  `json:"query"` // a word of a searching text which matches the term, empty if it's unknown.
+	Kind  string  `json:"kind"`  // KindMatch, KindPhrase, KindWildcard or KindFilter.
+	Field string  `json:"field"`
+	Term  string  `json:"term"`
+	Score float64 `json:"score"`
+}
+
+// toExplanation returns an explanation of bleve.
+func toExplanation(expl *bleve_search.Explanation) *Explanation {
+	if expl == nil {
+		return nil
+	}
+	e := &Explanation{Value: expl.Value, Message: expl.Message}
+	for _, child := range expl.Children {
+		e.Children = append(e.Children, toExplanation(child))
+	}
+	return e
+}
+
+// contributions returns scores of terms in an explanation, which are in order of scores.
+// each score is multiplied by coordination factors of its ancestors, so that they add up to a score of a hit.
+func contributions(im mapping.IndexMapping, text string, expl *bleve_search.Explanation) []*Contribution {
+	tokens, _ := tokenize(text)
+
+	var list []*Contribution
+	var walk func(e *bleve_search.Explanation, factor float64)
+	walk = func(e *bleve_search.Explanation, factor float64) {
+		if e == nil {
+			return
+		}
+		if m := termWeight.FindStringSubmatch(e.Message); m != nil {
+			c := &Contribution{Field: m[1], Term: m[2], Score: e.Value * factor}
+			labelContribution(im, tokens, c)
+			list = append(list, c)
+			return
+		}
+		for _, child := range e.Children {
+			if coordFactor.MatchString(child.Message) {
+				factor *= child.Value
+			}
+		}
+		for _, child := range e.Children {
+			if !coordFactor.MatchString(child.Message) {
+				walk(child, factor)
+			}
+		}
+	}
+	walk(expl, 1)
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	return list
+}
+
+// labelContribution sets a word of a searching text which matches a term of a contribution, and its kind.
+func labelContribution(im mapping.IndexMapping, tokens []*token, c *Contribution) {
+	for _, t := range tokens {
+		if t.kind != tokenTerm || !tokenHasField(t, c.Field) {
+			continue
+		}
+
+		var matched bool
+		kind := KindMatch
+		switch {
+		case isFilterField(t.field):
+			matched, kind = true, KindFilter
+			c.Term = t.text
+		case isWildcard(t.text) && !t.quoted:
+			matched, kind = wildcardRegexp(t.text).MatchString(c.Term), KindWildcard
+		default:
+			if t.quoted {
+				kind = KindPhrase
+			}
+			analyzer := im.AnalyzerNamed(fieldAnalyzer(im, c.Field))
+			if analyzer == nil {
+				continue
+			}
+			for _, term := range analyzer.Analyze([]byte(t.text)) {
+				if string(term.Term) == c.Term {
+					matched = true
+					break
+				}
+			}
+		}
+		if matched {
+			c.Query, c.Kind = tokenText(t), kind
+			return
+		}
+	}
+	c.Kind = KindMatch
+}
+
+// tokenHasField returns whether a token is matched against an indexed field.
+func tokenHasField(t *token, field string) bool {
+	if t.field == "" {
+		for name, boost := range fieldBoosts {
+			if boost > 0 && textFields[name] == field {
+				return true
+			}
+		}
+		return false
+	}
+	for _, fields := range []map[string]string{textFields, numericFields, dateFields, boolFields} {
+		if indexed, ok := fields[t.field]; ok {
+			return indexed == field
+		}
+	}
+	return false
+}
+
+// isFilterField returns whether a field of query syntax is metadata which isn't a text.
+func isFilterField(field string) bool {
+	for _, fields := range []map[string]string{numericFields, dateFields, boolFields} {
+		if _, ok := fields[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardRegexp returns a regexp of a wildcard, which matches lowercase terms.
+func wildcardRegexp(wildcard string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(strings.ToLower(wildcard))
+	pattern = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(pattern)
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// tokenText returns a token as it's written in a searching text.
+func tokenText(t *token) string {
+	text := t.text
+	if t.quoted {
+		text = strconv.Quote(text)
+	}
+	if t.field != "" {
+		text = t.field + ":" + text
+	}
+	return text
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blevesearch/bleve"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContributions(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	docs := []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Description: "A Commander for modern Go CLI interactions",
+			Topics: []string{"cli", "go"}, StargazersCount: 30000, Readme: "cobra is a library for creating powerful modern CLI applications"},
+		{Owner: "grpc-ecosystem", Repo: "grpc-gateway", FullName: "grpc-ecosystem/grpc-gateway", Description: "gRPC to JSON proxy generator",
+			Topics: []string{"grpc"}, StargazersCount: 15000, Readme: "The grpc gateway reads protobuf service definitions"},
+		{Owner: "allan", Repo: "hello", FullName: "allan/hello", Description: "hello cli", StargazersCount: 10, Readme: "hello world"},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+
+	type contribution struct {
+		query, kind, field, term string
+	}
+	tests := map[string]struct {
+		input  string
+		id     string
+		output []contribution
+	}{
+		"match": {input: "cobra", id: "spf13/cobra", output: []contribution{
+			{query: "cobra", kind: KindMatch, field: "name", term: "cobra"},
+			{query: "cobra", kind: KindMatch, field: "readme", term: "cobra"},
+		}},
+		"wildcard": {input: "hel*", id: "allan/hello", output: []contribution{
+			{query: "hel*", kind: KindWildcard, field: "name", term: "hello"},
+			{query: "hel*", kind: KindWildcard, field: "description", term: "hello"},
+			{query: "hel*", kind: KindWildcard, field: "readme", term: "hello"},
+		}},
+		"phrase": {input: `readme:"grpc gateway"`, id: "grpc-ecosystem/grpc-gateway", output: []contribution{
+			{query: `readme:"grpc gateway"`, kind: KindPhrase, field: "readme", term: "grpc"},
+			{query: `readme:"grpc gateway"`, kind: KindPhrase, field: "readme", term: "gatewai"},
+		}},
+		"filter": {input: "name:cobra stars:>1000", id: "spf13/cobra", output: []contribution{
+			{query: "name:cobra", kind: KindMatch, field: "name", term: "cobra"},
+			{query: "stars:>1000", kind: KindFilter, field: "stargazers_count", term: ">1000"},
+		}},
+	}
+
+	for name, t := range tests {
+		q, err := ParseQuery(t.input)
+		assert.NoError(err, name)
+		search := bleve.NewSearchRequest(q)
+		search.Explain = true
+		result, err := index.Search(search)
+		assert.NoError(err, name)
+
+		for _, hit := range result.Hits {
+			if hit.ID != t.id {
+				continue
+			}
+			list := contributions(index.Mapping(), t.input, hit.Expl)
+			var sum float64
+			var output []contribution
+			for _, c := range list {
+				sum += c.Score
+				output = append(output, contribution{query: c.Query, kind: c.Kind, field: c.Field, term: c.Term})
+			}
+			assert.ElementsMatch(t.output, output, name)
+			// contributions add up to a score.
+			assert.True(math.Abs(hit.Score-sum) < 1e-9, name)
+			assert.Equal(hit.Score, toExplanation(hit.Expl).Value, name)
+		}
+	}
+}
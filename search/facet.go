@@ -0,0 +1,139 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve"
+)
+
+const (
+	// FacetLanguage counts matched repositories per language of a language breakdown.
+	FacetLanguage = "languages"
+	// FacetTopic counts matched repositories per topic.
+	FacetTopic = "topics"
+	// FacetOwner counts matched repositories per owner.
+	FacetOwner = "owners"
+	// FacetStarredYear counts matched repositories per year when they were starred.
+	FacetStarredYear = "starred"
+	// FacetStars counts matched repositories per bucket of a star count.
+	FacetStars = "stars"
+
+	// facetSize is a count of the most frequent values of a facet.
+	facetSize = 10
+	// firstStarredYear is the first year of starred years, when github was launched.
+	firstStarredYear = 2008
+)
+
+var (
+	// Facets are all of facets in order of a summary.
+	Facets = []string{FacetLanguage, FacetTopic, FacetOwner, FacetStarredYear, FacetStars}
+
+	// termFacets maps a facet counting terms to an indexed field and a field of query syntax.
+	termFacets = map[string]struct{ field, syntax string }{
+		FacetLanguage: {field: "lang", syntax: "lang"},
+		FacetTopic:    {field: "topics", syntax: "topic"},
+		FacetOwner:    {field: "owner", syntax: "owner"},
+	}
+
+	// starBuckets are ranges of a star count, whose names are range texts of query syntax.
+	starBuckets = []struct {
+		name     string
+		min, max float64 // zero is unbounded.
+	}{
+		{name: "<10", max: 10},
+		{name: "10..99", min: 10, max: 100},
+		{name: "100..999", min: 100, max: 1000},
+		{name: "1000..9999", min: 1000, max: 10000},
+		{name: ">=10000", min: 10000},
+	}
+)
+
+// FacetCount is a count of matched repositories which have a value.
+// a filter is query syntax matching the value, which drills down a search.
+type FacetCount struct {
+	Value  string `json:"value"`
+	Count  int    `json:"count"`
+	Filter string `json:"filter"`
+}
+
+// addFacets adds facets to a search request.
+func addFacets(search *bleve.SearchRequest, facets []string) error {
+	for _, name := range facets {
+		if term, ok := termFacets[name]; ok {
+			search.AddFacet(name, bleve.NewFacetRequest(term.field, facetSize))
+			continue
+		}
+
+		switch name {
+		case FacetStarredYear:
+			thisYear := time.Now().Year()
+			fr := bleve.NewFacetRequest("starred_at", thisYear-firstStarredYear+1)
+			for year := firstStarredYear; year <= thisYear; year++ {
+				fr.AddDateTimeRange(strconv.Itoa(year),
+					time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC))
+			}
+			search.AddFacet(name, fr)
+		case FacetStars:
+			fr := bleve.NewFacetRequest("stargazers_count", len(starBuckets))
+			for _, bucket := range starBuckets {
+				min, max := bucket.min, bucket.max
+				switch {
+				case min == 0:
+					fr.AddNumericRange(bucket.name, nil, &max)
+				case max == 0:
+					fr.AddNumericRange(bucket.name, &min, nil)
+				default:
+					fr.AddNumericRange(bucket.name, &min, &max)
+				}
+			}
+			search.AddFacet(name, fr)
+		default:
+			return fmt.Errorf("[err] addFacets %w unknown facet %s", ErrInvalidParam, name)
+		}
+	}
+	return nil
+}
+
+// toFacetCounts returns counts of each facet of a search result.
+// terms are in order of counts, starred years are in order of the latest and star buckets are in order of stars.
+func toFacetCounts(result *bleve.SearchResult) map[string][]*FacetCount {
+	facets := map[string][]*FacetCount{}
+	for name, facet := range result.Facets {
+		counts := []*FacetCount{}
+		switch name {
+		case FacetStarredYear:
+			for _, dr := range facet.DateRanges {
+				counts = append(counts, &FacetCount{Value: dr.Name, Count: dr.Count, Filter: "starred:" + dr.Name})
+			}
+			sort.Slice(counts, func(i, j int) bool { return counts[i].Value > counts[j].Value })
+		case FacetStars:
+			order := map[string]int{}
+			for i, bucket := range starBuckets {
+				order[bucket.name] = i
+			}
+			for _, nr := range facet.NumericRanges {
+				counts = append(counts, &FacetCount{Value: nr.Name, Count: nr.Count, Filter: "stars:" + nr.Name})
+			}
+			sort.Slice(counts, func(i, j int) bool { return order[counts[i].Value] < order[counts[j].Value] })
+		default:
+			for _, term := range facet.Terms {
+				counts = append(counts, &FacetCount{Value: term.Term, Count: term.Count,
+					Filter: fieldFilter(termFacets[name].syntax, term.Term)})
+			}
+		}
+		facets[name] = counts
+	}
+	return facets
+}
+
+// fieldFilter returns query syntax matching a value of a field, which is quoted if it has spaces or operators.
+func fieldFilter(field, value string) string {
+	if strings.ContainsAny(value, " \t():*?") || strings.HasPrefix(value, "-") {
+		return field + ":\"" + value + "\""
+	}
+	return field + ":" + value
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToFacetCounts(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	docs := []*git.Starred{
+		{Owner: "spf13", FullName: "spf13/cobra", Description: "cli", Topics: []string{"cli", "go"}, Language: "Go",
+			StargazersCount: 30000, StarredAt: git.JsonTime{Time: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}},
+		{Owner: "spf13", FullName: "spf13/viper", Description: "cli config", Topics: []string{"config", "go"}, Language: "Go",
+			StargazersCount: 500, StarredAt: git.JsonTime{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{Owner: "allan", FullName: "allan/hello", Description: "cli", Topics: []string{"cli", "hello world"}, Language: "C++",
+			StargazersCount: 3, StarredAt: git.JsonTime{Time: time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+
+	tests := map[string]struct {
+		input  []string
+		output map[string][]*FacetCount
+		isErr  bool
+	}{
+		"terms": {input: []string{FacetTopic, FacetOwner}, output: map[string][]*FacetCount{
+			FacetTopic: {{Value: "cli", Count: 2, Filter: "topic:cli"}, {Value: "go", Count: 2, Filter: "topic:go"},
+				{Value: "config", Count: 1, Filter: "topic:config"}, {Value: "hello world", Count: 1, Filter: `topic:"hello world"`}},
+			FacetOwner: {{Value: "spf13", Count: 2, Filter: "owner:spf13"}, {Value: "allan", Count: 1, Filter: "owner:allan"}},
+		}},
+		"ranges": {input: []string{FacetStarredYear, FacetStars}, output: map[string][]*FacetCount{
+			FacetStarredYear: {{Value: "2023", Count: 2, Filter: "starred:2023"}, {Value: "2019", Count: 1, Filter: "starred:2019"}},
+			FacetStars: {{Value: "<10", Count: 1, Filter: "stars:<10"}, {Value: "100..999", Count: 1, Filter: "stars:100..999"},
+				{Value: ">=10000", Count: 1, Filter: "stars:>=10000"}},
+		}},
+		"unknown": {input: []string{"unknown"}, isErr: true},
+	}
+
+	q, err := ParseQuery("cli")
+	assert.NoError(err)
+	for name, t := range tests {
+		search := bleve.NewSearchRequest(q)
+		err := addFacets(search, t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			continue
+		}
+		result, err := index.Search(search)
+		assert.NoError(err, name)
+		assert.Equal(t.output, toFacetCounts(result), name)
+
+		// a filter of each value matches as many repositories as its count.
+		for _, counts := range t.output {
+			for _, count := range counts {
+				fq, err := ParseQuery("cli " + count.Filter)
+				assert.NoError(err, name)
+				filtered, err := index.Search(bleve.NewSearchRequest(fq))
+				assert.NoError(err, name)
+				assert.Equal(uint64(count.Count), filtered.Total, count.Filter)
+			}
+		}
+	}
+}
+
+func TestSearcher_SearchFacets(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.writeDBAndIndex(src, []*git.Starred{
+		{Owner: "allan", Repo: "ferris", FullName: "allan/ferris", Languages: map[string]int{"Rust": 9000, "Shell": 1000}},
+		{Owner: "allan", Repo: "script", FullName: "allan/script", Languages: map[string]int{"Shell": 9000, "Rust": 1000}},
+		{Owner: "allan", Repo: "gopher", FullName: "allan/gopher", Languages: map[string]int{"Go": 9000}},
+	}))
+
+	// a repository mostly written in a language is ranked higher.
+	found, facets, err := s.SearchFacets(context.Background(), "lang:rust", 0, FacetLanguage)
+	assert.NoError(err)
+	var names []string
+	for _, result := range found {
+		names = append(names, result.FullName)
+	}
+	assert.Equal([]string{"allan/ferris", "allan/script"}, names)
+	// languages of matched repositories are counted, not of all repositories.
+	assert.Equal([]*FacetCount{{Value: "rust", Count: 2, Filter: "lang:rust"}, {Value: "shell", Count: 2, Filter: "lang:shell"}}, facets[FacetLanguage])
+}
@@ -0,0 +1,47 @@
+package search
+
+import (
+	"html"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	bleve_search "github.com/blevesearch/bleve/search"
+	html_highlighter "github.com/blevesearch/bleve/search/highlight/highlighter/html"
+)
+
+const (
+	markBefore = "<mark>"
+	markAfter  = "</mark>"
+)
+
+// highlight sets fragments around matched terms of each matched field to a hit searched with locations.
+// fragments are escaped html with marked terms, which are made only for hits of a page.
+func highlight(index bleve.Index, hit *bleve_search.DocumentMatch) error {
+	highlighter, err := bleve.Config.Cache.HighlighterNamed(html_highlighter.Name)
+	if err != nil {
+		return err
+	}
+	doc, err := index.Document(hit.ID)
+	if err != nil || doc == nil {
+		return err
+	}
+	for field := range hit.Locations {
+		highlighter.BestFragmentsInField(hit, doc, field, 1)
+	}
+	return nil
+}
+
+// Snippet returns a plain text of the first fragment of README, which is empty if README isn't matched.
+// matched terms are wrapped by before and after, such as color codes.
+func (r *Result) Snippet(before, after string) string {
+	if fragments := r.Fragments["readme"]; len(fragments) != 0 {
+		return unmark(fragments[0], before, after)
+	}
+	return ""
+}
+
+// unmark returns a plain text of a fragment whose marked terms are wrapped by before and after.
+func unmark(fragment, before, after string) string {
+	text := strings.NewReplacer(markBefore, before, markAfter, after).Replace(fragment)
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult_Snippet(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	doc := &git.Starred{Owner: "grpc-ecosystem", Repo: "grpc-gateway", FullName: "grpc-ecosystem/grpc-gateway",
+		Description: "gRPC to JSON proxy generator",
+		Readme: "# grpc-gateway\n[![build](https://img.shields.io/badge/build.svg)](https://ci.example.com)\n" +
+			"The gRPC-Gateway is a plugin of <b>protoc</b>.\nIt reads protobuf service definitions & generates a reverse proxy server."}
+	assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+
+	tests := map[string]struct {
+		input  string
+		output string
+		fields []string
+	}{
+		"readme":      {input: "reverse proxy", output: "definitions & generates a [reverse] [proxy] se", fields: []string{"description", "readme"}},
+		"description": {input: "desc:json", output: "", fields: []string{"description"}},
+	}
+
+	for name, t := range tests {
+		q, err := ParseQuery(t.input)
+		assert.NoError(err, name)
+		search := bleve.NewSearchRequest(q)
+		search.IncludeLocations = true
+		result, err := index.Search(search)
+		assert.NoError(err, name)
+		assert.Len(result.Hits, 1, name)
+		assert.NoError(highlight(index, result.Hits[0]), name)
+
+		found := &Result{Starred: doc, Fragments: result.Hits[0].Fragments}
+		var fields []string
+		for field := range found.Fragments {
+			fields = append(fields, field)
+		}
+		assert.ElementsMatch(t.fields, fields, name)
+		snippet := found.Snippet("[", "]")
+		if t.output == "" {
+			assert.Empty(snippet, name)
+		} else {
+			assert.Contains(snippet, t.output, name)
+		}
+	}
+}
+
+func TestUnmark(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input  string
+		output string
+	}{
+		"plain":   {input: "hello world", output: "hello world"},
+		"marked":  {input: "a <mark>cli</mark> tool", output: "a [cli] tool"},
+		"escaped": {input: "&lt;mark&gt; &amp;\n <mark>go</mark>", output: "<mark> & [go]"},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, unmark(t.input, "[", "]"), name)
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	httpCacheBucketName = "http_cache"
+
+	// httpCacheExpiration is an age after which a cached response isn't used and is pruned.
+	// a response is fetched again at most once in the expiration, because 304 doesn't renew it.
+	httpCacheExpiration = 30 * 24 * time.Hour
+	// maxHTTPCacheSize is the largest size of cached responses, over which the oldest ones are pruned.
+	maxHTTPCacheSize = 64 << 20
+	// httpCacheTimeSize is a size of a cached time which prefixes a response.
+	httpCacheTimeSize = 8
+)
+
+// httpCache keeps responses of conditional requests of git clients in db.
+// a response is stored after a time when it's cached, so that old responses are pruned.
+type httpCache struct {
+	db *bolt.DB
+}
+
+// Get returns a cached response of a key, which isn't expired.
+func (c *httpCache) Get(key string) ([]byte, bool) {
+	var value []byte
+	c.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(httpCacheBucketName))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(key))
+		if cachedAt, ok := httpCacheTime(data); ok && time.Since(cachedAt) < httpCacheExpiration {
+			value = append([]byte{}, data[httpCacheTimeSize:]...)
+		}
+		return nil
+	})
+	return value, value != nil
+}
+
+// Set writes a response of a key with a current time, writes of concurrent requests are batched.
+func (c *httpCache) Set(key string, value []byte) error {
+	data := make([]byte, httpCacheTimeSize, httpCacheTimeSize+len(value))
+	binary.BigEndian.PutUint64(data, uint64(time.Now().Unix()))
+	data = append(data, value...)
+	return c.db.Batch(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(httpCacheBucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+}
+
+// prune deletes expired responses, and the oldest responses until cached responses are smaller than maxSize.
+// responses of unstarred repositories are pruned by an age, because keys are hashed.
+func (c *httpCache) prune(now time.Time, maxSize int) error {
+	type entry struct {
+		key      []byte
+		size     int
+		cachedAt time.Time
+	}
+
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(httpCacheBucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		var expired [][]byte
+		var entries []*entry
+		total := 0
+		if err := bucket.ForEach(func(k, v []byte) error {
+			key := append([]byte{}, k...)
+			cachedAt, ok := httpCacheTime(v)
+			if !ok || now.Sub(cachedAt) >= httpCacheExpiration {
+				expired = append(expired, key)
+				return nil
+			}
+			entries = append(entries, &entry{key: key, size: len(k) + len(v), cachedAt: cachedAt})
+			total += len(k) + len(v)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].cachedAt.Before(entries[j].cachedAt)
+		})
+		for _, e := range entries {
+			if total <= maxSize {
+				break
+			}
+			expired = append(expired, e.key)
+			total -= e.size
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("[err] prune %w", err)
+	}
+	return nil
+}
+
+// httpCacheTime returns a time when a response is cached.
+// a response cached by an old version isn't prefixed by a time, which starts with json.
+func httpCacheTime(data []byte) (time.Time, bool) {
+	if len(data) < httpCacheTimeSize || data[0] == '{' {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(data[:httpCacheTimeSize])), 0), true
+}
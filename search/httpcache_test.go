@@ -0,0 +1,76 @@
+package search
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCache_Prune(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+
+	cache := &httpCache{db: db}
+	put := func(key string, cachedAt time.Time, value string) {
+		data := make([]byte, httpCacheTimeSize)
+		binary.BigEndian.PutUint64(data, uint64(cachedAt.Unix()))
+		if cachedAt.IsZero() {
+			data = nil // a response cached by an old version.
+		}
+		assert.NoError(db.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(httpCacheBucketName))
+			if err != nil {
+				return err
+			}
+			return bucket.Put([]byte(key), append(data, value...))
+		}))
+	}
+
+	now := time.Now()
+	tests := map[string]struct {
+		maxSize int
+		kept    []string
+	}{
+		"expired": {maxSize: maxHTTPCacheSize, kept: []string{"new", "old"}},
+		"size":    {maxSize: 30, kept: []string{"new"}},
+	}
+
+	for name, t := range tests {
+		put("legacy", time.Time{}, `{"body":"legacy"}`)
+		put("expired", now.Add(-httpCacheExpiration-time.Hour), `{"body":"expired"}`)
+		put("old", now.Add(-time.Hour), `{"body":"old"}`)
+		put("new", now, `{"body":"new"}`)
+
+		_, ok := cache.Get("expired")
+		assert.False(ok, name)
+		_, ok = cache.Get("legacy")
+		assert.False(ok, name)
+
+		assert.NoError(cache.prune(now, t.maxSize), name)
+		var kept []string
+		assert.NoError(db.View(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(httpCacheBucketName)).ForEach(func(k, v []byte) error {
+				kept = append(kept, string(k))
+				return nil
+			})
+		}), name)
+		assert.Equal(t.kept, kept, name)
+	}
+
+	assert.NoError(cache.Set("set", []byte(`{"body":"set"}`)))
+	value, ok := cache.Get("set")
+	assert.True(ok)
+	assert.Equal(`{"body":"set"}`, string(value))
+}
@@ -0,0 +1,292 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/token/porter"
+	"github.com/blevesearch/bleve/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/registry"
+	"github.com/blevesearch/bleve/search/query"
+)
+
+const (
+	keywordAnalyzer    = "findgs_keyword"
+	wordsAnalyzer      = "findgs_words"
+	vectorAnalyzer     = "findgs_vector"
+	markdownAnalyzer   = "findgs_markdown"
+	markdownCharFilter = "findgs_markdown_noise"
+	htmlCharFilter     = "findgs_html"
+	blankCharFilter    = "findgs_blank"
+
+	// markdownNoise matches images, link targets, urls and html comments in markdown.
+	markdownNoise = `!\[[^\]]*\]\([^)]*\)|\]\([^)]*\)|https?://[^\s)>"']+|<!--[\s\S]*?-->`
+	// htmlTag matches html tags, which is the same as the html char filter of bleve.
+	htmlTag = `</?[!\w]+((\s+\w+(\s*=\s*(?:".*?"|'.*?'|[^'">\s]+))?)+\s*|\s*)/?>`
+)
+
+var (
+	// DefaultFieldBoosts are boosts of fields which words without a field are matched against.
+	DefaultFieldBoosts = map[string]float64{
+		"name":    4,
+		"topic":   3,
+		"owner":   2,
+		"desc":    2,
+		"heading": 2,
+		"readme":  1,
+		"code":    0.5,
+		"lang":    1,
+	}
+
+	fieldBoosts = copyBoosts(DefaultFieldBoosts)
+
+	// queryAnalyzers are analyzers of fields named differently from their paths,
+	// because bleve finds an analyzer of a query by a path rather than a field name.
+	queryAnalyzers = map[string]string{
+		"heading": markdownAnalyzer,
+		"readme":  markdownAnalyzer,
+		"code":    wordsAnalyzer,
+	}
+)
+
+// blankFilter replaces matches of a regexp with spaces of the same length,
+// so that offsets of terms point to an original text for highlighting.
+type blankFilter struct {
+	r *regexp.Regexp
+}
+
+func (f *blankFilter) Filter(input []byte) []byte {
+	return f.r.ReplaceAllFunc(input, func(match []byte) []byte {
+		return bytes.Repeat([]byte(" "), len(match))
+	})
+}
+
+func init() {
+	registry.RegisterCharFilter(blankCharFilter, func(config map[string]interface{}, cache *registry.Cache) (analysis.CharFilter, error) {
+		pattern, ok := config["regexp"].(string)
+		if !ok {
+			return nil, fmt.Errorf("[err] blankFilter %w regexp", ErrInvalidParam)
+		}
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("[err] blankFilter %w", err)
+		}
+		return &blankFilter{r: r}, nil
+	})
+}
+
+type boostableFieldQuery interface {
+	query.FieldableQuery
+	SetBoost(b float64)
+}
+
+// SetFieldBoosts overrides boosts of fields which words without a field are matched against.
+// a field with zero boost isn't matched.
+func SetFieldBoosts(boosts map[string]float64) error {
+	newBoosts := copyBoosts(DefaultFieldBoosts)
+	for field, boost := range boosts {
+		if _, ok := DefaultFieldBoosts[field]; !ok {
+			return fmt.Errorf("[err] SetFieldBoosts %w unknown field %s", ErrInvalidParam, field)
+		}
+		if boost < 0 {
+			return fmt.Errorf("[err] SetFieldBoosts %w negative boost %s", ErrInvalidParam, field)
+		}
+		newBoosts[field] = boost
+	}
+	fieldBoosts = newBoosts
+	return nil
+}
+
+// fieldAnalyzer returns a name of an analyzer which analyzes a query of an indexed field.
+func fieldAnalyzer(im mapping.IndexMapping, field string) string {
+	if name, ok := queryAnalyzers[field]; ok {
+		return name
+	}
+	return im.AnalyzerNameForPath(field)
+}
+
+func copyBoosts(boosts map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(boosts))
+	for field, boost := range boosts {
+		copied[field] = boost
+	}
+	return copied
+}
+
+// boostedQuery returns a disjunction of queries which are built for each boosted field.
+func boostedQuery(build func(field string) boostableFieldQuery) query.Query {
+	var names []string
+	for name, boost := range fieldBoosts {
+		if boost > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var disjuncts []query.Query
+	for _, name := range names {
+		q := build(textFields[name])
+		q.SetField(textFields[name])
+		q.SetBoost(fieldBoosts[name])
+		disjuncts = append(disjuncts, q)
+	}
+	if len(disjuncts) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	return bleve.NewDisjunctionQuery(disjuncts...)
+}
+
+// newIndexMapping returns an index mapping for starred repositories.
+func newIndexMapping() mapping.IndexMapping {
+	im := bleve.NewIndexMapping()
+	im.DefaultAnalyzer = standard.Name
+
+	// lowercase keyword for exact matching such as owner and topics.
+	if err := im.AddCustomAnalyzer(keywordAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{lowercase.Name},
+	}); err != nil {
+		panic(err)
+	}
+
+	// markdown text without images, link targets, urls and html tags, which are blanked to keep offsets.
+	for name, pattern := range map[string]string{markdownCharFilter: markdownNoise, htmlCharFilter: htmlTag} {
+		if err := im.AddCustomCharFilter(name, map[string]interface{}{
+			"type":   blankCharFilter,
+			"regexp": pattern,
+		}); err != nil {
+			panic(err)
+		}
+	}
+	if err := im.AddCustomAnalyzer(markdownAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []string{markdownCharFilter, htmlCharFilter},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, en.StopName, porter.Name},
+	}); err != nil {
+		panic(err)
+	}
+
+	// lowercase words without stemming and stop words, which are a dictionary of suggestions.
+	if err := im.AddCustomAnalyzer(wordsAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []string{markdownCharFilter, htmlCharFilter},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	}); err != nil {
+		panic(err)
+	}
+
+	// lowercase words without stop words, which are embedded to vectors of a semantic search.
+	if err := im.AddCustomAnalyzer(vectorAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []string{markdownCharFilter, htmlCharFilter},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, en.StopName},
+	}); err != nil {
+		panic(err)
+	}
+
+	keywordField := func() *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = keywordAnalyzer
+		fm.IncludeInAll = false
+		fm.IncludeTermVectors = false
+		return fm
+	}
+	textField := func(analyzer string) *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = analyzer
+		return fm
+	}
+	numericField := func() *mapping.FieldMapping {
+		fm := bleve.NewNumericFieldMapping()
+		fm.IncludeInAll = false
+		return fm
+	}
+	booleanField := func() *mapping.FieldMapping {
+		fm := bleve.NewBooleanFieldMapping()
+		fm.IncludeInAll = false
+		return fm
+	}
+	// words of a name, a description and readme are indexed together without storing them.
+	wordsField := func() *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Name = wordsFieldName
+		fm.Analyzer = wordsAnalyzer
+		fm.Store = false
+		fm.IncludeInAll = false
+		fm.IncludeTermVectors = false
+		return fm
+	}
+	dateTimeField := func() *mapping.FieldMapping {
+		fm := bleve.NewDateTimeFieldMapping()
+		fm.IncludeInAll = false
+		return fm
+	}
+
+	starred := bleve.NewDocumentStaticMapping()
+
+	// full_name is indexed as a keyword and as a text named "name".
+	fullName := keywordField()
+	name := textField(standard.Name)
+	name.Name = "name"
+	starred.AddFieldMappingsAt("full_name", fullName, name, wordsField())
+
+	starred.AddFieldMappingsAt("provider", keywordField())
+	starred.AddFieldMappingsAt("owner", keywordField())
+	starred.AddFieldMappingsAt("repo", textField(standard.Name))
+	starred.AddFieldMappingsAt("topics", keywordField())
+	starred.AddFieldMappingsAt("description", textField(en.AnalyzerName), wordsField())
+
+	// README is indexed as headings, prose named "readme" and code blocks, and raw markdown isn't indexed.
+	headings := textField(markdownAnalyzer)
+	headings.Name = "heading"
+	starred.AddFieldMappingsAt("readme_headings", headings, wordsField())
+	prose := textField(markdownAnalyzer)
+	prose.Name = "readme"
+	starred.AddFieldMappingsAt("readme_prose", prose, wordsField())
+	code := textField(wordsAnalyzer)
+	code.Name = "code"
+	starred.AddFieldMappingsAt("readme_code", code)
+
+	// metadata for filtering.
+	homepage := textField(standard.Name)
+	homepage.IncludeInAll = false
+	starred.AddFieldMappingsAt("homepage", homepage)
+	starred.AddFieldMappingsAt("language", keywordField())
+
+	// languages repeated by their shares, which words without a field are also matched against.
+	starred.AddFieldMappingsAt("lang", keywordField())
+	starred.AddFieldMappingsAt("license", keywordField())
+	starred.AddFieldMappingsAt("default_branch", keywordField())
+	starred.AddFieldMappingsAt("archived", booleanField())
+	starred.AddFieldMappingsAt("disabled", booleanField())
+	starred.AddFieldMappingsAt("fork", booleanField())
+	starred.AddFieldMappingsAt("template", booleanField())
+
+	starred.AddFieldMappingsAt("watchers_count", numericField())
+	starred.AddFieldMappingsAt("stargazers_count", numericField())
+	starred.AddFieldMappingsAt("forks_count", numericField())
+	starred.AddFieldMappingsAt("open_issues_count", numericField())
+	starred.AddFieldMappingsAt("size", numericField())
+
+	starred.AddFieldMappingsAt("starred_at", dateTimeField())
+	starred.AddFieldMappingsAt("created_at", dateTimeField())
+	starred.AddFieldMappingsAt("updated_at", dateTimeField())
+	starred.AddFieldMappingsAt("pushed_at", dateTimeField())
+
+	im.DefaultMapping = starred
+	return im
+}
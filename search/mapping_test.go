@@ -0,0 +1,76 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIndexMapping(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	docs := []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Topics: []string{"CLI"}, Readme: "a library"},
+		{Owner: "urfave", Repo: "cli", FullName: "urfave/cli", Readme: "# Usage\n[![badge](https://img.shields.io/cobra.svg)](https://github.com/cobra) better than cobra for writing applications\n" +
+			"```go\napp := &cli.App{Name: \"greet\"}\n```"},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+
+	tests := map[string]struct {
+		input  string
+		output []string
+	}{
+		"name outranks readme": {input: "cobra", output: []string{"spf13/cobra", "urfave/cli"}},
+		"keyword owner":        {input: "owner:SPF13", output: []string{"spf13/cobra"}},
+		"keyword topic":        {input: "topic:cli", output: []string{"spf13/cobra"}},
+		"readme stemming":      {input: "readme:application", output: []string{"urfave/cli"}},
+		"readme without urls":  {input: "readme:shields", output: nil},
+		"readme without code":  {input: "readme:greet", output: nil},
+		"heading":              {input: "heading:usage", output: []string{"urfave/cli"}},
+		"code":                 {input: `code:"cli.app"`, output: []string{"urfave/cli"}},
+	}
+
+	for name, t := range tests {
+		q, err := ParseQuery(t.input)
+		assert.NoError(err, name)
+		result, err := index.Search(bleve.NewSearchRequest(q))
+		assert.NoError(err, name)
+		var ids []string
+		for _, hit := range result.Hits {
+			ids = append(ids, hit.ID)
+		}
+		assert.Equal(t.output, ids, name)
+	}
+}
+
+func TestSetFieldBoosts(t *testing.T) {
+	assert := assert.New(t)
+	defer SetFieldBoosts(nil)
+
+	tests := map[string]struct {
+		input  map[string]float64
+		output map[string]float64
+		isErr  bool
+	}{
+		"default":  {input: nil, output: DefaultFieldBoosts},
+		"override": {input: map[string]float64{"readme": 0}, output: map[string]float64{"name": 4, "topic": 3, "owner": 2, "desc": 2, "heading": 2, "readme": 0, "code": 0.5, "lang": 1}},
+		"unknown":  {input: map[string]float64{"stars": 1}, isErr: true},
+		"negative": {input: map[string]float64{"name": -1}, isErr: true},
+	}
+
+	for name, t := range tests {
+		err := SetFieldBoosts(t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(t.output, fieldBoosts, name)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	fenceLine       = regexp.MustCompile("^\\s{0,3}(```+|~~~+)")
+	atxHeadingLine  = regexp.MustCompile(`^\s{0,3}#{1,6}(\s+(.*?))?(\s+#+)?\s*$`)
+	setextLine      = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	breakLine       = regexp.MustCompile(`^\s{0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+	tableBorderLine = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	linkDefLine     = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S+`)
+	listItemLine    = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+
+	htmlComment   = regexp.MustCompile(`<!--[\s\S]*?-->`)
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\](\([^)]*\)|\[[^\]]*\])`)
+	markdownLink  = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	autoLink      = regexp.MustCompile(`<(https?://|mailto:)[^>]*>|https?://[^\s)>"']+`)
+	htmlTagText   = regexp.MustCompile(htmlTag)
+)
+
+// markdown is README separated into headings, prose and code blocks,
+// whose badges, images, html tags and link targets are stripped.
+type markdown struct {
+	Headings string
+	Prose    string
+	Code     string
+}
+
+// parseMarkdown separates README into headings, prose and code blocks.
+// fenced and indented code blocks are code, ATX and setext headings are headings, and the rest is prose.
+// link texts are kept without their targets, and images such as badges are removed with their alt texts.
+func parseMarkdown(text string) *markdown {
+	text = htmlComment.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"), "")
+
+	var headings, prose, code []string
+	var fence string
+	var inList, inIndented bool
+	prevBlank, prevProse := true, false
+	for _, line := range strings.Split(text, "\n") {
+		// fenced code is closed by the same fence.
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			} else {
+				code = append(code, line)
+			}
+			continue
+		}
+		if m := fenceLine.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			prevBlank, prevProse = false, false
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			prevBlank, prevProse = true, false
+			continue
+		}
+
+		// indented code follows a blank line, except continuations of a list item.
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		if indented && !inList && (prevBlank || inIndented) {
+			code = append(code, strings.TrimPrefix(strings.TrimPrefix(line, "\t"), "    "))
+			inIndented, prevBlank, prevProse = true, false, false
+			continue
+		}
+		inIndented = false
+		if listItemLine.MatchString(line) {
+			inList = true
+		} else if !indented {
+			inList = false
+		}
+
+		switch {
+		case atxHeadingLine.MatchString(line):
+			if heading := cleanMarkdownLine(atxHeadingLine.FindStringSubmatch(line)[2]); heading != "" {
+				headings = append(headings, heading)
+			}
+			prevProse = false
+		case prevProse && setextLine.MatchString(line):
+			// a line underlined by = or - is a heading.
+			headings = append(headings, prose[len(prose)-1])
+			prose = prose[:len(prose)-1]
+			prevProse = false
+		case breakLine.MatchString(line), tableBorderLine.MatchString(line), linkDefLine.MatchString(line):
+			prevProse = false
+		default:
+			if cleaned := cleanMarkdownLine(line); cleaned != "" {
+				prose = append(prose, cleaned)
+				prevProse = true
+			} else {
+				prevProse = false
+			}
+		}
+		prevBlank = false
+	}
+
+	return &markdown{
+		Headings: strings.Join(headings, "\n"),
+		Prose:    strings.Join(prose, "\n"),
+		Code:     strings.Join(code, "\n"),
+	}
+}
+
+// cleanMarkdownLine returns a plain text of a line without images, link targets, urls, html tags and markers of inline code.
+func cleanMarkdownLine(line string) string {
+	line = markdownImage.ReplaceAllString(line, "")
+	line = markdownLink.ReplaceAllString(line, "$1")
+	line = autoLink.ReplaceAllString(line, "")
+	line = htmlTagText.ReplaceAllString(line, " ")
+	line = strings.ReplaceAll(line, "`", "")
+	line = html.UnescapeString(line)
+	return strings.Join(strings.Fields(line), " ")
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input  string
+		output *markdown
+	}{
+		"badges": {
+			input: "# cobra\n[![Go](https://github.com/spf13/cobra/workflows/Go/badge.svg)](https://github.com/spf13/cobra/actions) [![GoDoc][doc-img]][doc]\n" +
+				"Cobra is a [library](https://pkg.go.dev/github.com/spf13/cobra) for CLI.\n\n[doc-img]: https://godoc.org/badge.svg\n[doc]: https://godoc.org",
+			output: &markdown{Headings: "cobra", Prose: "Cobra is a library for CLI."},
+		},
+		"html": {
+			input:  "<p align=\"center\"><img src=\"logo.png\" alt=\"logo\"></p>\n<!-- comment\nlines -->\nA <b>fast</b> &amp; small tool, see <https://example.com>.",
+			output: &markdown{Prose: "A fast & small tool, see ."},
+		},
+		"setext": {
+			input:  "Install\n=======\nRun it.\n\n---\n\nUsage\n-----",
+			output: &markdown{Headings: "Install\nUsage", Prose: "Run it."},
+		},
+		"fenced code": {
+			input:  "## Example\n```go\nfunc main() {}\n```\n~~~\n$ go get\n~~~\nuse `go run`.",
+			output: &markdown{Headings: "Example", Prose: "use go run.", Code: "func main() {}\n$ go get"},
+		},
+		"indented code": {
+			input:  "Build:\n\n    make build\n    make test\n\n- item\n    continued",
+			output: &markdown{Prose: "Build:\n- item\ncontinued", Code: "make build\nmake test"},
+		},
+		"table": {
+			input:  "| flag | description |\n|------|:-----------:|\n| -v | verbose |",
+			output: &markdown{Prose: "| flag | description |\n| -v | verbose |"},
+		},
+	}
+
+	for name, t := range tests {
+		assert.Equal(t.output, parseMarkdown(t.input), name)
+	}
+}
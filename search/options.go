@@ -0,0 +1,133 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blevesearch/bleve/search/query"
+)
+
+const (
+	SortScore     = "score"
+	SortStars     = "stars"
+	SortForks     = "forks"
+	SortStarredAt = "starred_at"
+	SortPushedAt  = "pushed_at"
+	SortName      = "name"
+)
+
+var (
+	// Sorts are orders of results, which are descending except SortName.
+	Sorts = []string{SortScore, SortStars, SortForks, SortStarredAt, SortPushedAt, SortName}
+
+	// sortFields are sort orders of bleve per a sort, whose ties are broken by a score and an id.
+	sortFields = map[string][]string{
+		SortScore:     {"-_score", "_id"},
+		SortStars:     {"-stargazers_count", "-_score", "_id"},
+		SortForks:     {"-forks_count", "-_score", "_id"},
+		SortStarredAt: {"-starred_at", "-_score", "_id"},
+		SortPushedAt:  {"-pushed_at", "-_score", "_id"},
+		SortName:      {"full_name", "_id"},
+	}
+)
+
+// SearchOptions are options of a search.
+type SearchOptions struct {
+	MinScore float64
+	Sort     string   // one of Sorts, results are sorted by score if it's empty.
+	Filters  []string // range filters such as stars>1000, pushed>=30d and starred:2023-01..2023-06.
+	Facets   []string // facets such as FacetTopic and FacetStars.
+	Explain  bool     // whether an explanation of a score of each hit is returned.
+	Offset   int      // a count of found repositories which are skipped.
+	Limit    int      // a maximum count of returned repositories, all of them are returned if it's zero.
+	Mode     string   // one of Modes, repositories are ranked by keywords if it's empty.
+}
+
+// Page is a page of found repositories.
+type Page struct {
+	Results []*Result
+	Facets  map[string][]*FacetCount // counts of all of matched repositories regardless of a minimum score.
+	Total   int                      // a count of found repositories equal to or higher than a minimum score.
+	Offset  int
+}
+
+// sortOrder returns a sort order of bleve.
+func (o *SearchOptions) sortOrder() ([]string, error) {
+	if o.Sort == "" {
+		return sortFields[SortScore], nil
+	}
+	order, ok := sortFields[strings.ToLower(o.Sort)]
+	if !ok {
+		return nil, fmt.Errorf("%w unknown sort %s", ErrInvalidParam, o.Sort)
+	}
+	return order, nil
+}
+
+// mode returns a lowercase ranking mode.
+func (o *SearchOptions) mode() (string, error) {
+	if o.Mode == "" {
+		return ModeKeyword, nil
+	}
+	mode := strings.ToLower(o.Mode)
+	for _, m := range Modes {
+		if m == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("%w unknown mode %s", ErrInvalidParam, o.Mode)
+}
+
+// filterQueries returns range queries of filters, and filters of query syntax such as stars:>1000.
+func (o *SearchOptions) filterQueries() ([]query.Query, []string, error) {
+	var queries []query.Query
+	var syntax []string
+	for _, filter := range o.Filters {
+		q, err := ParseFilter(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		// filters narrow hits without adding to their scores.
+		if boostable, ok := q.(query.BoostableQuery); ok {
+			boostable.SetBoost(0)
+		}
+		field, text := splitFilter(filter)
+		queries = append(queries, q)
+		syntax = append(syntax, field+":"+text)
+	}
+	return queries, syntax, nil
+}
+
+// ParseFilter parses a range filter of a number or a date into a bleve range query.
+//   - numbers: stars>1000 forks>=10 issues<10 size:10..100
+//   - dates: pushed>2024-01-01 starred:2023-01..2023-06 created<2020
+//   - days ago: pushed>=30d (pushed within 30 days)
+func ParseFilter(text string) (query.Query, error) {
+	field, rangeText := splitFilter(strings.TrimSpace(text))
+	if rangeText == "" {
+		return nil, fmt.Errorf("[err] ParseFilter %w empty range %q", ErrInvalidQuery, text)
+	}
+	if indexed, ok := numericFields[field]; ok {
+		q, err := numericRangeQuery(indexed, rangeText)
+		if err != nil {
+			return nil, fmt.Errorf("[err] ParseFilter %w", err)
+		}
+		return q, nil
+	}
+	if indexed, ok := dateFields[field]; ok {
+		q, err := dateRangeQuery(indexed, rangeText)
+		if err != nil {
+			return nil, fmt.Errorf("[err] ParseFilter %w", err)
+		}
+		return q, nil
+	}
+	return nil, fmt.Errorf("[err] ParseFilter %w unknown filter %q", ErrInvalidQuery, text)
+}
+
+// splitFilter splits a filter such as stars>1000 and stars:>1000 to a lowercase field and a range text.
+func splitFilter(text string) (field string, rangeText string) {
+	ix := strings.IndexAny(text, ":<>=")
+	if ix < 0 {
+		return strings.ToLower(text), ""
+	}
+	return strings.ToLower(text[:ix]), strings.TrimPrefix(text[ix:], ":")
+}
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	bleve_search "github.com/blevesearch/bleve/search"
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearcher_SearchIndexOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	now := time.Now().UTC()
+	docs := []*git.Starred{
+		{Owner: "spf13", FullName: "spf13/cobra", Description: "cli", StargazersCount: 30000, ForksCount: 2000,
+			StarredAt: git.JsonTime{Time: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}, PushedAt: git.JsonTime{Time: now.AddDate(0, 0, -3)}},
+		{Owner: "urfave", FullName: "urfave/cli", Description: "cli cli cli", StargazersCount: 20000, ForksCount: 3000,
+			StarredAt: git.JsonTime{Time: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)}, PushedAt: git.JsonTime{Time: now.AddDate(0, -6, 0)}},
+		{Owner: "allan", FullName: "allan/hello", Description: "hello cli", StargazersCount: 3, ForksCount: 1,
+			StarredAt: git.JsonTime{Time: time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)}, PushedAt: git.JsonTime{Time: now.AddDate(-2, 0, 0)}},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+	s := &searcher{index: index}
+
+	tests := map[string]struct {
+		input  *SearchOptions
+		output []string
+		isErr  bool
+	}{
+		"stars":       {input: &SearchOptions{Sort: SortStars}, output: []string{"spf13/cobra", "urfave/cli", "allan/hello"}},
+		"forks":       {input: &SearchOptions{Sort: "FORKS"}, output: []string{"urfave/cli", "spf13/cobra", "allan/hello"}},
+		"starred_at":  {input: &SearchOptions{Sort: SortStarredAt}, output: []string{"urfave/cli", "spf13/cobra", "allan/hello"}},
+		"pushed_at":   {input: &SearchOptions{Sort: SortPushedAt}, output: []string{"spf13/cobra", "urfave/cli", "allan/hello"}},
+		"name":        {input: &SearchOptions{Sort: SortName}, output: []string{"allan/hello", "spf13/cobra", "urfave/cli"}},
+		"stars range": {input: &SearchOptions{Sort: SortName, Filters: []string{"stars>1000", "forks:<=2000"}}, output: []string{"spf13/cobra"}},
+		"days ago":    {input: &SearchOptions{Sort: SortName, Filters: []string{"pushed>=30d"}}, output: []string{"spf13/cobra"}},
+		"date range":  {input: &SearchOptions{Sort: SortName, Filters: []string{"starred:2023-01..2023-06"}}, output: []string{"spf13/cobra"}},
+		"page":        {input: &SearchOptions{Sort: SortName, Offset: 1, Limit: 1}, output: []string{"spf13/cobra"}},
+		"min score":   {input: &SearchOptions{Sort: SortName, Offset: 1, Limit: 1, MinScore: 0.1}, output: []string{"allan/hello", "spf13/cobra", "urfave/cli"}},
+		"unknown":     {input: &SearchOptions{Sort: "watchers"}, isErr: true},
+		"wrong":       {input: &SearchOptions{Filters: []string{"name>1"}}, isErr: true},
+	}
+
+	for name, t := range tests {
+		result, err := s.searchIndex(context.Background(), "cli", 0, t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			continue
+		}
+		var ids []string
+		for _, hit := range result.Hits {
+			ids = append(ids, hit.ID)
+			// locations are made only for a page.
+			assert.Empty(hit.Locations, name)
+		}
+		assert.Equal(t.output, ids, name)
+	}
+
+	// filters don't add to scores.
+	unfiltered, err := s.searchIndex(context.Background(), "cli", 0, &SearchOptions{})
+	assert.NoError(err)
+	filtered, err := s.searchIndex(context.Background(), "cli", 0, &SearchOptions{Filters: []string{"stars>0", "pushed>=30d"}})
+	assert.NoError(err)
+	scores := map[string]float64{}
+	for _, hit := range unfiltered.Hits {
+		scores[hit.ID] = hit.Score
+	}
+	assert.Len(filtered.Hits, 1)
+	for _, hit := range filtered.Hits {
+		assert.InDelta(scores[hit.ID], hit.Score, 1e-9, hit.ID)
+	}
+}
+
+func TestSearcher_SearchPage(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+	var starred []*git.Starred
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("repo-%02d", i)
+		starred = append(starred, &git.Starred{Owner: "allan", Repo: name, FullName: "allan/" + name, Readme: "readme of " + name})
+	}
+	assert.NoError(s.writeDBAndIndex(src, starred))
+
+	// a page of found repositories is returned with a total of them.
+	page, err := s.SearchWith(context.Background(), "readme", &SearchOptions{Sort: SortName, Offset: 8, Limit: 10})
+	assert.NoError(err)
+	assert.Equal(12, page.Total)
+	assert.Equal(8, page.Offset)
+	assert.Len(page.Results, 4)
+	assert.Equal("allan/repo-08", page.Results[0].FullName)
+	assert.NotEmpty(page.Results[0].Fragments)
+
+	// an offset out of found repositories returns an empty page.
+	page, err = s.SearchWith(context.Background(), "readme", &SearchOptions{Offset: 20, Limit: 10})
+	assert.NoError(err)
+	assert.Equal(12, page.Total)
+	assert.Empty(page.Results)
+
+	_, err = s.SearchWith(context.Background(), "readme", &SearchOptions{Offset: -1})
+	assert.Error(err)
+}
+
+func TestParseFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]struct {
+		input string
+		isErr bool
+	}{
+		"number":       {input: "stars>1000"},
+		"syntax":       {input: "stars:>=1000"},
+		"between":      {input: "forks:10..100"},
+		"date":         {input: "pushed>2024-01-01"},
+		"days ago":     {input: "pushed>=30d"},
+		"wrong number": {input: "stars>many", isErr: true},
+		"wrong date":   {input: "pushed>yesterday", isErr: true},
+		"empty":        {input: "stars", isErr: true},
+		"text field":   {input: "name:cobra", isErr: true},
+	}
+
+	for name, t := range tests {
+		_, err := ParseFilter(t.input)
+		assert.Equal(t.isErr, err != nil, name)
+	}
+}
+
+func TestPageHits(t *testing.T) {
+	assert := assert.New(t)
+
+	var hits []*bleve_search.DocumentMatch
+	for i := 0; i < 5; i++ {
+		hits = append(hits, &bleve_search.DocumentMatch{ID: fmt.Sprintf("%d", i)})
+	}
+
+	tests := map[string]struct {
+		offset int
+		limit  int
+		output []string
+	}{
+		"first":     {offset: 0, limit: 2, output: []string{"0", "1"}},
+		"last":      {offset: 4, limit: 2, output: []string{"4"}},
+		"unlimited": {offset: 2, limit: 0, output: []string{"2", "3", "4"}},
+		"over":      {offset: 5, limit: 2, output: nil},
+	}
+
+	for name, t := range tests {
+		var ids []string
+		for _, hit := range pageHits(hits, t.offset, t.limit) {
+			ids = append(ids, hit.ID)
+		}
+		assert.Equal(t.output, ids, name)
+	}
+}
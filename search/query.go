@@ -0,0 +1,569 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+)
+
+var (
+	ErrInvalidQuery = errors.New("[err] Invalid query")
+
+	// textFields maps a field name of query syntax to an indexed text field.
+	textFields = map[string]string{
+		"name":        "name",
+		"owner":       "owner",
+		"repo":        "repo",
+		"topic":       "topics",
+		"topics":      "topics",
+		"desc":        "description",
+		"description": "description",
+		"readme":      "readme",
+		"heading":     "heading",
+		"code":        "code",
+		"provider":    "provider",
+		"language":    "language",
+		"lang":        "lang",
+		"license":     "license",
+		"branch":      "default_branch",
+		"homepage":    "homepage",
+	}
+
+	// keywordFields are indexed text fields without term vectors, so that a quoted text is matched as a whole.
+	keywordFields = map[string]bool{
+		"owner":          true,
+		"topics":         true,
+		"provider":       true,
+		"language":       true,
+		"lang":           true,
+		"license":        true,
+		"default_branch": true,
+	}
+
+	// numericFields maps a field name of query syntax to an indexed numeric field.
+	numericFields = map[string]string{
+		"stars":    "stargazers_count",
+		"forks":    "forks_count",
+		"watchers": "watchers_count",
+		"issues":   "open_issues_count",
+		"size":     "size",
+	}
+
+	// boolFields maps a field name of query syntax to an indexed boolean field.
+	boolFields = map[string]string{
+		"archived": "archived",
+		"disabled": "disabled",
+		"fork":     "fork",
+		"template": "template",
+	}
+
+	// dateFields maps a field name of query syntax to an indexed datetime field.
+	dateFields = map[string]string{
+		"starred": "starred_at",
+		"created": "created_at",
+		"updated": "updated_at",
+		"pushed":  "pushed_at",
+	}
+
+	// dateLayouts are accepted date formats with a period which a date of the format covers.
+	dateLayouts = []struct {
+		layout string
+		years  int
+		months int
+		days   int
+	}{
+		{layout: time.RFC3339},
+		{layout: "2006-01-02", days: 1},
+		{layout: "2006-01", months: 1},
+		{layout: "2006", years: 1},
+	}
+)
+
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind   tokenKind
+	field  string
+	text   string
+	quoted bool
+}
+
+type queryParser struct {
+	tokens    []*token
+	pos       int
+	fuzziness int // an edit distance of words, which are matched exactly if it's zero.
+}
+
+// ParseQuery parses a searching text into a bleve query.
+// words without a field are matched against fields with boosts of SetFieldBoosts.
+//
+// The syntax supports the following expressions.
+//   - words: cli tool (consecutive words are matched together against all fields)
+//   - wildcards: hello*
+//   - phrases: "grpc gateway"
+//   - fields: name:cobra topic:cli owner:spf13 desc:proxy readme:"grpc gateway" provider:gitlab
+//   - metadata: language:go lang:rust license:mit branch:main homepage:example archived:false fork:true template:true
+//   - ranges: stars:>1000 forks:10..100 issues:<10 size:<1024 pushed:>=2024-01-01 starred:2023 pushed:>=30d
+//   - boolean: AND, OR, NOT, -negation and (grouping)
+func ParseQuery(text string) (query.Query, error) {
+	return parseQuery(text, 0)
+}
+
+// parseQuery parses a searching text into a bleve query whose words are matched within fuzziness of an edit distance.
+// phrases, wildcards and metadata are matched exactly.
+func parseQuery(text string, fuzziness int) (query.Query, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, fmt.Errorf("[err] ParseQuery %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("[err] ParseQuery %w", ErrInvalidQuery)
+	}
+
+	p := &queryParser{tokens: tokens, fuzziness: fuzziness}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("[err] ParseQuery %w", err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("[err] ParseQuery %w unexpected %q", ErrInvalidQuery, p.tokens[p.pos].text)
+	}
+	return q, nil
+}
+
+// tokenize splits a searching text to tokens.
+func tokenize(text string) ([]*token, error) {
+	var tokens []*token
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, &token{kind: tokenLeftParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, &token{kind: tokenRightParen, text: ")"})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, &token{kind: tokenNot, text: "-"})
+			i++
+		case r == '"':
+			phrase, next, err := readPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, &token{kind: tokenTerm, text: phrase, quoted: true})
+			i = next
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				// field:"phrase"
+				if runes[i] == ':' && i+1 < len(runes) && runes[i+1] == '"' {
+					break
+				}
+				i++
+			}
+			word := string(runes[start:i])
+			if i < len(runes) && runes[i] == ':' {
+				phrase, next, err := readPhrase(runes, i+1)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, newPhraseToken(word, phrase))
+				i = next
+				continue
+			}
+
+			switch word {
+			case "AND", "&&":
+				tokens = append(tokens, &token{kind: tokenAnd, text: word})
+			case "OR", "||":
+				tokens = append(tokens, &token{kind: tokenOr, text: word})
+			case "NOT":
+				tokens = append(tokens, &token{kind: tokenNot, text: word})
+			default:
+				tokens = append(tokens, newTermToken(word))
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// readPhrase reads a quoted phrase starting at runes[start] and returns it with a next position.
+func readPhrase(runes []rune, start int) (string, int, error) {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			return string(runes[start+1 : i]), i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w unterminated phrase", ErrInvalidQuery)
+}
+
+// newTermToken returns a term token, a word with an unknown field is treated as a plain word.
+func newTermToken(word string) *token {
+	ix := strings.Index(word, ":")
+	if ix <= 0 || ix == len(word)-1 {
+		return &token{kind: tokenTerm, text: word}
+	}
+	field := strings.ToLower(word[:ix])
+	if isKnownField(field) {
+		return &token{kind: tokenTerm, field: field, text: word[ix+1:]}
+	}
+	return &token{kind: tokenTerm, text: word}
+}
+
+// newPhraseToken returns a phrase token of field:"phrase", a phrase with an unknown field is treated as a plain phrase with the field.
+func newPhraseToken(field, phrase string) *token {
+	if isKnownField(strings.ToLower(field)) {
+		return &token{kind: tokenTerm, field: strings.ToLower(field), text: phrase, quoted: true}
+	}
+	return &token{kind: tokenTerm, text: field + ":" + phrase, quoted: true}
+}
+
+// isKnownField returns whether a field of query syntax is a text, numeric, date or boolean field.
+func isKnownField(field string) bool {
+	if _, ok := textFields[field]; ok {
+		return true
+	}
+	if _, ok := numericFields[field]; ok {
+		return true
+	}
+	if _, ok := dateFields[field]; ok {
+		return true
+	}
+	_, ok := boolFields[field]
+	return ok
+}
+
+func (p *queryParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return nil
+}
+
+// parseOr parses expressions joined by OR.
+func (p *queryParser) parseOr() (query.Query, error) {
+	var disjuncts []query.Query
+	for {
+		q, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		disjuncts = append(disjuncts, q)
+
+		if t := p.peek(); t == nil || t.kind != tokenOr {
+			break
+		}
+		p.pos++
+	}
+
+	if len(disjuncts) == 1 {
+		return disjuncts[0], nil
+	}
+	return bleve.NewDisjunctionQuery(disjuncts...), nil
+}
+
+// parseAnd parses expressions joined by AND or by spaces.
+// consecutive plain words joined by spaces are merged to a single match query.
+func (p *queryParser) parseAnd() (query.Query, error) {
+	var must, mustNot []query.Query
+	var words []string
+	explicit := false
+
+	flushWords := func() {
+		if len(words) != 0 {
+			must = append(must, termQuery(&token{kind: tokenTerm, text: strings.Join(words, " ")}, p.fuzziness))
+			words = nil
+		}
+	}
+
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokenOr || t.kind == tokenRightParen {
+			break
+		}
+		if t.kind == tokenAnd {
+			if len(must) == 0 && len(mustNot) == 0 && len(words) == 0 {
+				return nil, fmt.Errorf("%w unexpected %q", ErrInvalidQuery, t.text)
+			}
+			explicit = true
+			p.pos++
+			continue
+		}
+
+		if isPlainWord(t) {
+			if explicit {
+				flushWords()
+			}
+			words = append(words, t.text)
+			explicit = false
+			p.pos++
+			continue
+		}
+
+		flushWords()
+		explicit = false
+		q, negated, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if negated {
+			mustNot = append(mustNot, q)
+		} else {
+			must = append(must, q)
+		}
+	}
+	flushWords()
+
+	switch {
+	case len(must) == 0 && len(mustNot) == 0:
+		return nil, fmt.Errorf("%w empty expression", ErrInvalidQuery)
+	case len(mustNot) == 0 && len(must) == 1:
+		return must[0], nil
+	case len(mustNot) == 0:
+		return bleve.NewConjunctionQuery(must...), nil
+	default:
+		return query.NewBooleanQuery(must, nil, mustNot), nil
+	}
+}
+
+// parseUnary parses a negated or a primary expression and returns whether it is negated.
+func (p *queryParser) parseUnary() (query.Query, bool, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, false, fmt.Errorf("%w unexpected end", ErrInvalidQuery)
+	}
+	if t.kind == tokenNot {
+		p.pos++
+		q, negated, err := p.parseUnary()
+		if err != nil {
+			return nil, false, err
+		}
+		return q, !negated, nil
+	}
+	q, err := p.parsePrimary()
+	return q, false, err
+}
+
+// parsePrimary parses a grouped expression or a term.
+func (p *queryParser) parsePrimary() (query.Query, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("%w unexpected end", ErrInvalidQuery)
+	}
+	switch t.kind {
+	case tokenLeftParen:
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.peek(); end == nil || end.kind != tokenRightParen {
+			return nil, fmt.Errorf("%w unclosed parenthesis", ErrInvalidQuery)
+		}
+		p.pos++
+		return q, nil
+	case tokenTerm:
+		p.pos++
+		if field, ok := numericFields[t.field]; ok {
+			return numericRangeQuery(field, t.text)
+		}
+		if field, ok := dateFields[t.field]; ok {
+			return dateRangeQuery(field, t.text)
+		}
+		if field, ok := boolFields[t.field]; ok {
+			return boolQuery(field, t.text)
+		}
+		return termQuery(t, p.fuzziness), nil
+	default:
+		return nil, fmt.Errorf("%w unexpected %q", ErrInvalidQuery, t.text)
+	}
+}
+
+// isPlainWord returns whether a token is a word without field, quote and wildcard.
+func isPlainWord(t *token) bool {
+	return t.kind == tokenTerm && t.field == "" && !t.quoted && !isWildcard(t.text)
+}
+
+func isWildcard(text string) bool {
+	return strings.ContainsAny(text, "*?")
+}
+
+// termQuery returns a bleve query for a text term token.
+// a term without a field is matched against boosted fields, and a word is matched within fuzziness.
+func termQuery(t *token, fuzziness int) query.Query {
+	build := func(field string) boostableFieldQuery {
+		var q boostableFieldQuery
+		switch {
+		case t.quoted && keywordFields[field]:
+			q = bleve.NewMatchQuery(t.text)
+		case t.quoted:
+			phrase := bleve.NewMatchPhraseQuery(t.text)
+			phrase.Analyzer = queryAnalyzers[field]
+			q = phrase
+		case isWildcard(t.text):
+			q = bleve.NewWildcardQuery(strings.ToLower(t.text))
+		default:
+			match := bleve.NewMatchQuery(t.text)
+			match.SetFuzziness(fuzziness)
+			match.Analyzer = queryAnalyzers[field]
+			q = match
+		}
+		q.SetField(field)
+		return q
+	}
+
+	if t.field == "" {
+		return boostedQuery(build)
+	}
+	return build(textFields[t.field])
+}
+
+// boolQuery returns a boolean query for a text such as true and false.
+func boolQuery(field, text string) (query.Query, error) {
+	b, err := strconv.ParseBool(strings.ToLower(text))
+	if err != nil {
+		return nil, fmt.Errorf("%w wrong boolean %q", ErrInvalidQuery, text)
+	}
+	q := bleve.NewBoolFieldQuery(b)
+	q.SetField(field)
+	return q, nil
+}
+
+// splitRange splits a range text(>v, >=v, <v, <=v, a..b, v) to an operator and operands.
+func splitRange(text string) (op string, left string, right string) {
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(text, prefix) {
+			return prefix, strings.TrimPrefix(text, prefix), ""
+		}
+	}
+	if ix := strings.Index(text, ".."); ix >= 0 {
+		return "..", text[:ix], text[ix+2:]
+	}
+	return "=", text, ""
+}
+
+// numericRangeQuery returns a numeric range query for a range text.
+func numericRangeQuery(field, text string) (query.Query, error) {
+	op, left, right := splitRange(text)
+	parse := func(s string) (*float64, error) {
+		if s == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w wrong number %q", ErrInvalidQuery, s)
+		}
+		return &f, nil
+	}
+
+	l, err := parse(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parse(right)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil && r == nil {
+		return nil, fmt.Errorf("%w empty range %q", ErrInvalidQuery, text)
+	}
+
+	inclusive, exclusive := true, false
+	var q *query.NumericRangeQuery
+	switch op {
+	case ">":
+		q = bleve.NewNumericRangeInclusiveQuery(l, nil, &exclusive, nil)
+	case ">=":
+		q = bleve.NewNumericRangeInclusiveQuery(l, nil, &inclusive, nil)
+	case "<":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, l, nil, &exclusive)
+	case "<=":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, l, nil, &inclusive)
+	case "..":
+		q = bleve.NewNumericRangeInclusiveQuery(l, r, &inclusive, &inclusive)
+	default:
+		q = bleve.NewNumericRangeInclusiveQuery(l, l, &inclusive, &inclusive)
+	}
+	q.SetField(field)
+	return q, nil
+}
+
+// parseDatePeriod parses a date text and returns the start and the end of the period which it covers.
+// days ago such as 30d covers the day.
+func parseDatePeriod(s string) (time.Time, time.Time, error) {
+	if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") && days >= 0 {
+		t := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	for _, l := range dateLayouts {
+		t, err := time.Parse(l.layout, s)
+		if err != nil {
+			continue
+		}
+		end := t.AddDate(l.years, l.months, l.days)
+		if end.Equal(t) {
+			end = t.Add(time.Second)
+		}
+		return t, end, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w wrong date %q", ErrInvalidQuery, s)
+}
+
+// dateRangeQuery returns a date range query for a range text.
+func dateRangeQuery(field, text string) (query.Query, error) {
+	op, left, right := splitRange(text)
+	if left == "" && right == "" {
+		return nil, fmt.Errorf("%w empty range %q", ErrInvalidQuery, text)
+	}
+
+	var start, end time.Time
+	if left != "" {
+		lstart, lend, err := parseDatePeriod(left)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case ">":
+			start = lend
+		case ">=":
+			start = lstart
+		case "<":
+			end = lstart
+		case "<=":
+			end = lend
+		default:
+			start, end = lstart, lend
+		}
+	}
+	if right != "" {
+		_, rend, err := parseDatePeriod(right)
+		if err != nil {
+			return nil, err
+		}
+		end = rend
+	}
+
+	inclusive, exclusive := true, false
+	q := bleve.NewDateRangeInclusiveQuery(start, end, &inclusive, &exclusive)
+	q.SetField(field)
+	return q, nil
+}
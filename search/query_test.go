@@ -0,0 +1,92 @@
+package search
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	docs := []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Description: "A Commander for modern Go CLI interactions",
+			Topics: []string{"cli", "go"}, Language: "Go", License: "Apache-2.0", OpenIssuesCount: 200, StargazersCount: 30000, PushedAt: git.JsonTime{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+			Readme: "cobra is a library for creating powerful modern CLI applications"},
+		{Owner: "grpc-ecosystem", Repo: "grpc-gateway", FullName: "grpc-ecosystem/grpc-gateway", Description: "gRPC to JSON proxy generator",
+			Topics: []string{"grpc", "rest-api"}, Language: "Go", Languages: map[string]int{"Go": 9000, "Shell": 500, "Makefile": 50}, License: "BSD-3-Clause", Fork: true, StargazersCount: 15000, PushedAt: git.JsonTime{Time: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+			Readme: "The grpc gateway reads protobuf service definitions and generates a reverse proxy server"},
+		{Owner: "allan", Repo: "hello", FullName: "allan/hello", Description: "archived hello cli",
+			Topics: []string{"cli"}, Language: "C++", License: "MIT", Archived: true, StargazersCount: 10, PushedAt: git.JsonTime{Time: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Readme: "hello world"},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+
+	tests := map[string]struct {
+		input  string
+		output []string
+		isErr  bool
+	}{
+		"words":           {input: "cobra proxy", output: []string{"grpc-ecosystem/grpc-gateway", "spf13/cobra"}},
+		"wildcard":        {input: "hel*", output: []string{"allan/hello"}},
+		"field":           {input: "name:cobra topic:cli", output: []string{"spf13/cobra"}},
+		"phrase":          {input: `readme:"grpc gateway"`, output: []string{"grpc-ecosystem/grpc-gateway"}},
+		"quoted keyword":  {input: `topic:"rest-api"`, output: []string{"grpc-ecosystem/grpc-gateway"}},
+		"negation":        {input: "topic:cli -archived", output: []string{"spf13/cobra"}},
+		"not":             {input: "topic:cli NOT archived", output: []string{"spf13/cobra"}},
+		"or":              {input: "name:cobra OR name:hello", output: []string{"allan/hello", "spf13/cobra"}},
+		"group":           {input: "(name:cobra OR name:hello) -archived", output: []string{"spf13/cobra"}},
+		"and":             {input: "cli AND hello", output: []string{"allan/hello"}},
+		"stars":           {input: "stars:>1000", output: []string{"grpc-ecosystem/grpc-gateway", "spf13/cobra"}},
+		"stars range":     {input: "stars:10..20000", output: []string{"allan/hello", "grpc-ecosystem/grpc-gateway"}},
+		"pushed":          {input: "pushed:>=2023-01-01 topic:cli", output: []string{"spf13/cobra"}},
+		"pushed year":     {input: "pushed:2023", output: []string{"grpc-ecosystem/grpc-gateway"}},
+		"unknown field":   {input: "http://hello", output: []string{"allan/hello"}},
+		"unknown phrase":  {input: `cobra:"proxy"`, output: nil},
+		"unknown word":    {input: "cobra:proxy", output: nil},
+		"language":        {input: "language:go", output: []string{"grpc-ecosystem/grpc-gateway", "spf13/cobra"}},
+		"language symbol": {input: "language:c++", output: []string{"allan/hello"}},
+		"lang":            {input: "lang:go", output: []string{"grpc-ecosystem/grpc-gateway", "spf13/cobra"}},
+		"lang breakdown":  {input: "lang:shell", output: []string{"grpc-ecosystem/grpc-gateway"}},
+		"lang minor":      {input: "lang:makefile", output: nil},
+		"lang words":      {input: "shell proxy", output: []string{"grpc-ecosystem/grpc-gateway"}},
+		"license":         {input: "license:mit", output: []string{"allan/hello"}},
+		"archived":        {input: "topic:cli archived:false", output: []string{"spf13/cobra"}},
+		"not fork":        {input: "-fork:true language:go", output: []string{"spf13/cobra"}},
+		"issues":          {input: "issues:>=100", output: []string{"spf13/cobra"}},
+		"wrong boolean":   {input: "archived:maybe", isErr: true},
+		"empty":           {input: "", isErr: true},
+		"unclosed":        {input: "(cli", isErr: true},
+		"unterminated":    {input: `"grpc gateway`, isErr: true},
+		"wrong number":    {input: "stars:>many", isErr: true},
+		"wrong date":      {input: "pushed:>yesterday", isErr: true},
+		"leading and":     {input: "AND cli", isErr: true},
+		"unexpected end":  {input: "cli OR", isErr: true},
+	}
+
+	for name, t := range tests {
+		q, err := ParseQuery(t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			continue
+		}
+		result, err := index.Search(bleve.NewSearchRequest(q))
+		assert.NoError(err, name)
+		var ids []string
+		for _, hit := range result.Hits {
+			ids = append(ids, hit.ID)
+		}
+		sort.Strings(ids)
+		assert.Equal(t.output, ids, name)
+	}
+}
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/blevesearch/bleve"
 	bleve_search "github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
 	"github.com/boltdb/bolt"
 	"github.com/fatih/color"
 	"github.com/gjbae1212/findgs/git"
@@ -22,8 +24,15 @@ import (
 
 const (
 	dbFileName          = "cache.db"
+	indexDirName        = "index.bleve"
 	userBucketName      = "user"
 	starredBucketSuffix = "starred"
+
+	// indexVersion should be changed when an index mapping is changed, so that an old index is rebuilt.
+	indexVersion = "9"
+
+	// scoreSaturation is a score of bleve which is normalized to 0.5.
+	scoreSaturation = 0.1
 )
 
 var (
@@ -33,34 +42,85 @@ var (
 	configOnce sync.Once
 	configErr  error
 
-	maxSize = 10000
+	indexVersionKey = []byte("findgs_version")
+	indexOwnerKey   = []byte("findgs_owner")
+	indexDirtyKey   = []byte("findgs_dirty")
 )
 
 type Searcher interface {
 	CreateIndex() error
+	CreateIndexContext(ctx context.Context) error
 	Search(text string, minScore float64) ([]*Result, error)
+	SearchContext(ctx context.Context, text string, minScore float64) ([]*Result, error)
+	SearchFacets(ctx context.Context, text string, minScore float64, facets ...string) ([]*Result, map[string][]*FacetCount, error)
+	SearchWith(ctx context.Context, text string, opts *SearchOptions) (*Page, error)
+	Explain(ctx context.Context, text string, minScore float64) ([]*Result, error)
+	Suggest(ctx context.Context, text string) (string, error)
+	Similar(ctx context.Context, fullName string, opts *SearchOptions) (*Page, error)
 	TotalDoc() (int, error)
+	Close() error
+}
+
+// Source is a provider such as github(enterprise), gitlab and gitea whose starred repositories are indexed.
+type Source struct {
+	Provider string
+	BaseURL  string
+	Token    string
+	API      string // git.APIGraphQL fetches starred of github through GraphQL, REST is used if it's empty.
+
+	// Languages fetches a language breakdown of each repository, which costs a request per repository.
+	Languages bool
+}
+
+type source struct {
+	key   string // an account identity such as login@host, which is resolved by resolveAccount.
+	token string // never persisted.
+	git   git.Git
+
+	languages bool // whether a language breakdown is fetched.
 }
 
 type searcher struct {
-	gitToken string
-	dbPath   string
-	git      git.Git
-	db       *bolt.DB
-	index    bleve.Index
+	dbPath    string
+	indexPath string
+	sources   []*source
+	db        *bolt.DB
+	index     bleve.Index
+	semantic  *semanticIndex // a latent semantic space loaded from the db, which is nil until it's loaded.
+	vectors   bool           // whether vectors of starred are computed while indexing, which vector and hybrid modes search.
+}
+
+// Option sets an optional behavior of a searcher.
+type Option func(*searcher)
+
+// WithSemantic sets whether vectors of starred are computed while indexing, which vector and hybrid modes search.
+func WithSemantic(enabled bool) Option {
+	return func(s *searcher) {
+		s.vectors = enabled
+	}
 }
 
 type Result struct {
 	*git.Starred
-	Score float64
+	Score     float64             // between 0 and 1, which doesn't depend on other matches.
+	RawScore  float64             // a score of bleve, which isn't comparable across queries.
+	Fragments map[string][]string // fragments around matched terms per field, which are escaped html with <mark> tags.
+	Fuzzy     bool                // whether it's found by fuzzy matching, not by exact matching.
+
+	// how a score is computed, which is set by Explain.
+	Explanation   *Explanation
+	Contributions []*Contribution
 }
 
-// ClearAll clears all of cached data such as boltDB.
+// ClearAll clears all of cached data such as boltDB and index.
 func ClearAll() error {
 	cfgPath, err := ConfigPath()
 	if err != nil {
 		return fmt.Errorf("[err] ClearAll %w", err)
 	}
+	if err := os.RemoveAll(filepath.Join(cfgPath, indexDirName)); err != nil {
+		return fmt.Errorf("[err] ClearAll %w", err)
+	}
 	return os.RemoveAll(filepath.Join(cfgPath, dbFileName))
 }
 
@@ -88,36 +148,114 @@ func ConfigPath() (string, error) {
 }
 
 // NewSearcher returns an object implemented Searcher.
-func NewSearcher(token string) (Searcher, error) {
+// starred repositories of Github and other sources are indexed side by side.
+func NewSearcher(token string, others ...*Source) (Searcher, error) {
 	if token == "" {
 		return nil, fmt.Errorf("[err] NewSearcher %w", ErrInvalidParam)
 	}
+	return NewSearcherFromSources(append([]*Source{{Provider: git.ProviderGithub, Token: token}}, others...))
+}
+
+// NewSearcherFromSources returns an object implemented Searcher which indexes starred repositories of sources.
+// each source is cached in a separate bucket per host.
+func NewSearcherFromSources(sources []*Source, opts ...Option) (Searcher, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("[err] NewSearcherFromSources %w", ErrInvalidParam)
+	}
 
 	cfgPath, err := ConfigPath()
 	if err != nil {
-		return nil, fmt.Errorf("[err] NewSearcher %w", err)
+		return nil, fmt.Errorf("[err] NewSearcherFromSources %w", err)
 	}
 	dbPath := filepath.Join(cfgPath, dbFileName)
-
-	// make git client
-	git, err := git.NewGit(token)
-	if err != nil {
-		return nil, fmt.Errorf("[err] NewSearcher %w", err)
+	indexPath := filepath.Join(cfgPath, indexDirName)
+
+	// make git clients
+	var srcs []*source
+	hosts := map[string]bool{}
+	for _, src := range sources {
+		if src == nil || src.Token == "" {
+			return nil, fmt.Errorf("[err] NewSearcherFromSources %w", ErrInvalidParam)
+		}
+		g, err := newSourceGit(src)
+		if err != nil {
+			return nil, fmt.Errorf("[err] NewSearcherFromSources %w", err)
+		}
+		if hosts[g.Host()] {
+			return nil, fmt.Errorf("[err] NewSearcherFromSources duplicated host %s %w", g.Host(), ErrInvalidParam)
+		}
+		hosts[g.Host()] = true
+		srcs = append(srcs, &source{token: src.Token, git: g, languages: src.Languages})
 	}
 
 	// make bolt db
 	db, err := bolt.Open(dbPath, os.ModePerm, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
-		return nil, fmt.Errorf("[err] NewSearcher fail db %w.(maybe already running findgs)", err)
+		return nil, fmt.Errorf("[err] NewSearcherFromSources fail db %w.(maybe already running findgs)", err)
+	}
+
+	// responses of git clients are cached for conditional requests, whose old ones are pruned on start.
+	cache := &httpCache{db: db}
+	if err := cache.prune(time.Now(), maxHTTPCacheSize); err != nil {
+		color.Yellow("[err] http cache %s", err.Error())
+	}
+	for _, src := range srcs {
+		src.git.SetCache(cache)
 	}
 
 	// make index
-	index, err := bleve.NewMemOnly(bleve.NewIndexMapping())
+	index, err := openIndex(indexPath)
 	if err != nil {
-		return nil, fmt.Errorf("[err] NewSearcher fail db %w", err)
+		db.Close()
+		return nil, fmt.Errorf("[err] NewSearcherFromSources fail index %w", err)
 	}
 
-	return &searcher{git: git, db: db, index: index, gitToken: token, dbPath: dbPath}, nil
+	s := &searcher{sources: srcs, db: db, index: index, dbPath: dbPath, indexPath: indexPath}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// caches of old versions keyed by raw tokens are migrated once before accounts of sources are resolved.
+	if err := s.migrateLegacyAccounts(); err != nil {
+		index.Close()
+		db.Close()
+		return nil, fmt.Errorf("[err] NewSearcherFromSources %w", err)
+	}
+	return s, nil
+}
+
+// openIndex opens an index on disk, or makes new one if it doesn't exist or is collapsed.
+func openIndex(path string) (bleve.Index, error) {
+	index, err := bleve.Open(path)
+	switch {
+	case err == nil:
+		return index, nil
+	case errors.Is(err, bleve.ErrorIndexPathDoesNotExist):
+	default:
+		color.Yellow("[err] collapse index, so delete index")
+		if suberr := os.RemoveAll(path); suberr != nil {
+			return nil, fmt.Errorf("[err] openIndex %w", suberr)
+		}
+	}
+
+	index, err = bleve.New(path, newIndexMapping())
+	if err != nil {
+		return nil, fmt.Errorf("[err] openIndex %w", err)
+	}
+	return index, nil
+}
+
+// Close closes a database and an index.
+func (s *searcher) Close() error {
+	indexErr := s.index.Close()
+	dbErr := s.db.Close()
+	if indexErr != nil {
+		return fmt.Errorf("[err] Close %w", indexErr)
+	}
+	if dbErr != nil {
+		return fmt.Errorf("[err] Close %w", dbErr)
+	}
+	return nil
 }
 
 // TotalDoc returns total of documents.
@@ -126,77 +264,329 @@ func (s *searcher) TotalDoc() (int, error) {
 	return int(count), err
 }
 
-// Search executes full text search.
+// Search executes full text search with a query syntax of ParseQuery.
 func (s *searcher) Search(text string, minScore float64) ([]*Result, error) {
-	text = strings.TrimSpace(text)
-	if text == "" {
-		return []*Result{}, nil
+	return s.SearchContext(context.Background(), text, minScore)
+}
+
+// SearchContext is Search which is cancelled by ctx.
+func (s *searcher) SearchContext(ctx context.Context, text string, minScore float64) ([]*Result, error) {
+	list, _, err := s.SearchFacets(ctx, text, minScore)
+	return list, err
+}
+
+// SearchFacets is SearchContext which also returns counts of facets such as FacetTopic and FacetStars.
+// facets count all of matched repositories regardless of minScore.
+func (s *searcher) SearchFacets(ctx context.Context, text string, minScore float64, facets ...string) ([]*Result, map[string][]*FacetCount, error) {
+	page, err := s.SearchWith(ctx, text, &SearchOptions{MinScore: minScore, Facets: facets})
+	if err != nil {
+		return nil, map[string][]*FacetCount{}, err
 	}
+	return page.Results, page.Facets, nil
+}
+
+// SearchWith searches a page of repositories with options such as a sort, range filters, facets, an offset and a limit.
+func (s *searcher) SearchWith(ctx context.Context, text string, opts *SearchOptions) (*Page, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	return s.search(ctx, text, opts)
+}
 
-	summary := map[string]*bleve_search.DocumentMatch{}
+// Explain is SearchContext which also returns an explanation of a score of each hit,
+// such as which query, field and term contributed how much.
+func (s *searcher) Explain(ctx context.Context, text string, minScore float64) ([]*Result, error) {
+	page, err := s.search(ctx, text, &SearchOptions{MinScore: minScore, Explain: true})
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
 
-	// search using matchquery from index.
-	search := bleve.NewSearchRequestOptions(bleve.NewMatchQuery(text), maxSize, 0, false)
-	search.SortBy([]string{"-_score", "_id"})
-	searchResult, err := s.index.Search(search)
+// search executes full text search, and returns a page of found starred.
+func (s *searcher) search(ctx context.Context, text string, opts *SearchOptions) (*Page, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("[err] Search %w", ErrInvalidParam)
+	}
+	page := &Page{Results: []*Result{}, Facets: map[string][]*FacetCount{}, Offset: opts.Offset}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return page, nil
+	}
+	mode, err := opts.mode()
 	if err != nil {
 		return nil, fmt.Errorf("[err] Search %w", err)
 	}
-	for _, d := range searchResult.Hits {
-		if d.Score >= minScore {
-			summary[d.ID] = d
+	if mode != ModeKeyword {
+		searchResult, err := s.searchSemantic(ctx, text, mode, opts)
+		if err != nil {
+			return nil, fmt.Errorf("[err] Search %w", err)
 		}
+		return s.newPage(ctx, searchResult, opts, nil, text), nil
 	}
 
-	// search using wildcardQuery from index.
-	search = bleve.NewSearchRequestOptions(bleve.NewWildcardQuery(text), maxSize, 0, false)
-	search.SortBy([]string{"-_score", "_id"})
-	searchResult, err = s.index.Search(search)
+	searchResult, err := s.searchIndex(ctx, text, 0, opts)
 	if err != nil {
 		return nil, fmt.Errorf("[err] Search %w", err)
 	}
-	for _, d := range searchResult.Hits {
-		if d.Score >= minScore {
-			summary[d.ID] = d
+	// words are matched fuzzily if few repositories are matched exactly, such as a typo.
+	var exact map[string]bool // ids matched exactly, which is nil unless words are matched fuzzily.
+	if fuzziness > 0 && searchResult.Total < minExactHits {
+		fuzzyResult, err := s.searchIndex(ctx, text, fuzziness, opts)
+		if err != nil {
+			return nil, fmt.Errorf("[err] Search %w", err)
+		}
+		if fuzzyResult.Total > searchResult.Total {
+			// exact hits out of a page are searched again, which are fewer than minExactHits.
+			exactHits := searchResult.Hits
+			if len(exactHits) < int(searchResult.Total) {
+				exactResult, err := s.searchIndex(ctx, text, 0, &SearchOptions{Filters: opts.Filters, Limit: minExactHits})
+				if err != nil {
+					return nil, fmt.Errorf("[err] Search %w", err)
+				}
+				exactHits = exactResult.Hits
+			}
+			exact = map[string]bool{}
+			for _, d := range exactHits {
+				exact[d.ID] = true
+			}
+			searchResult = fuzzyResult
 		}
 	}
+	// filters are labeled as query syntax in explanations.
+	explained := text
+	if opts.Explain {
+		_, syntax, _ := opts.filterQueries()
+		explained = strings.Join(append([]string{text}, syntax...), " ")
+	}
 
-	// get a detailed starred information
-	var list []*Result
-	s.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(starredBucketName(s.gitToken)))
-		if bucket == nil {
-			return nil
+	return s.newPage(ctx, searchResult, opts, exact, explained), nil
+}
+
+// newPage returns a page of found starred from a result of bleve, which has hits of the page or all of hits.
+// ids matched exactly are nil unless words are matched fuzzily, and explained is a text which labels explanations.
+func (s *searcher) newPage(ctx context.Context, searchResult *bleve.SearchResult, opts *SearchOptions, exact map[string]bool, explained string) *Page {
+	page := &Page{Results: []*Result{}, Facets: map[string][]*FacetCount{}, Offset: opts.Offset}
+	page.Total = int(searchResult.Total)
+	page.Facets = toFacetCounts(searchResult)
+
+	// keyword scores are normalized without other hits, so that a minimum score means the same for every query.
+	// scores of vector and hybrid modes are already between 0 and 1.
+	score := normalizeScore
+	if mode, _ := opts.mode(); mode != ModeKeyword {
+		score = func(raw float64) float64 { return raw }
+	}
+	hits := searchResult.Hits
+	if opts.MinScore > 0 {
+		hits = nil
+		for _, d := range searchResult.Hits {
+			if score(d.Score) >= opts.MinScore {
+				hits = append(hits, d)
+			}
 		}
-		for _, doc := range summary {
-			data := bucket.Get([]byte(doc.ID))
+		page.Total = len(hits)
+	}
+	// hits searched from an offset are already a page.
+	if searchResult.Request == nil || searchResult.Request.From == 0 {
+		hits = pageHits(hits, opts.Offset, opts.Limit)
+	}
+
+	// locations and fragments are made only for hits of a page.
+	if err := s.locate(ctx, searchResult.Request, hits); err != nil {
+		color.Yellow("[err] locate %s", err)
+	}
+	for _, d := range hits {
+		if err := highlight(s.index, d); err != nil {
+			color.Yellow("[err] highlight %s", d.ID)
+		}
+	}
+
+	// get a detailed starred information in order of hits.
+	s.db.View(func(tx *bolt.Tx) error {
+		for _, doc := range hits {
+			src, key := s.sourceOf(doc.ID)
+			if src == nil {
+				continue
+			}
+			bucket := tx.Bucket([]byte(starredBucketName(src.key)))
+			if bucket == nil {
+				continue
+			}
+			data := bucket.Get([]byte(key))
 			var starred *git.Starred
 			if err := json.Unmarshal(data, &starred); err == nil {
-				list = append(list, &Result{Starred: starred, Score: doc.Score})
+				result := &Result{Starred: starred, Score: score(doc.Score),
+					RawScore: doc.Score, Fragments: doc.Fragments, Fuzzy: exact != nil && !exact[doc.ID]}
+				if opts.Explain {
+					// contributions are scaled in proportion, so that they sum to a normalized score.
+					result.Explanation = toExplanation(doc.Expl)
+					result.Contributions = contributions(s.index.Mapping(), explained, doc.Expl)
+					for _, c := range result.Contributions {
+						if doc.Score > 0 {
+							c.Score = c.Score / doc.Score * result.Score
+						}
+					}
+				}
+				page.Results = append(page.Results, result)
 			}
 		}
 		return nil
 	})
-	sort.Slice(list, func(i, j int) bool { return list[i].Score > list[j].Score })
-	return list, nil
+	return page
+}
+
+// searchIndex searches the index using a parsed query whose words are matched within fuzziness.
+// words, phrases, wildcards and filters are combined to a single query, so that a score of a hit is computed once.
+func (s *searcher) searchIndex(ctx context.Context, text string, fuzziness int, opts *SearchOptions) (*bleve.SearchResult, error) {
+	q, err := parseQuery(text, fuzziness)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchQuery(ctx, q, opts)
+}
+
+// searchQuery searches the index using a query with filters, a sort and facets of options.
+// only hits of a page are returned without locations of terms, but all of hits are returned if a minimum score is set,
+// because hits under it are skipped before paging.
+func (s *searcher) searchQuery(ctx context.Context, q query.Query, opts *SearchOptions) (*bleve.SearchResult, error) {
+	filters, _, err := opts.filterQueries()
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) != 0 {
+		q = bleve.NewConjunctionQuery(append([]query.Query{q}, filters...)...)
+	}
+	order, err := opts.sortOrder()
+	if err != nil {
+		return nil, err
+	}
+	from, size := opts.Offset, opts.Limit
+	if opts.MinScore > 0 {
+		from, size = 0, 0
+	}
+	if size == 0 {
+		total, err := s.index.DocCount()
+		if err != nil {
+			return nil, err
+		}
+		size = int(total)
+	}
+	search := bleve.NewSearchRequestOptions(q, size, from, opts.Explain)
+	search.SortBy(order)
+	if err := addFacets(search, opts.Facets); err != nil {
+		return nil, err
+	}
+	return s.index.SearchInContext(ctx, search)
+}
+
+// locate sets locations of matched terms to hits, which are searched again only among them by a request.
+func (s *searcher) locate(ctx context.Context, request *bleve.SearchRequest, hits []*bleve_search.DocumentMatch) error {
+	if request == nil || len(hits) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(hits))
+	for _, d := range hits {
+		ids = append(ids, d.ID)
+	}
+	search := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(request.Query, bleve.NewDocIDQuery(ids)), len(ids), 0, false)
+	search.IncludeLocations = true
+	result, err := s.index.SearchInContext(ctx, search)
+	if err != nil {
+		return err
+	}
+	locations := map[string]bleve_search.FieldTermLocationMap{}
+	for _, d := range result.Hits {
+		locations[d.ID] = d.Locations
+	}
+	for _, d := range hits {
+		d.Locations = locations[d.ID]
+	}
+	return nil
+}
+
+// pageHits returns hits from offset to offset+limit, all of hits from offset if limit is zero.
+func pageHits(hits []*bleve_search.DocumentMatch, offset, limit int) []*bleve_search.DocumentMatch {
+	if offset >= len(hits) {
+		return nil
+	}
+	hits = hits[offset:]
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// normalizeScore returns a score between 0 and 1 by saturating a score of bleve, which is half at scoreSaturation.
+// a score of bleve is normalized by weights of a query, so that a saturated score doesn't depend on other hits.
+func normalizeScore(score float64) float64 {
+	if score <= 0 {
+		return 0
+	}
+	return score / (score + scoreSaturation)
 }
 
 // CreateIndex is indexing to bleve.Index.
 func (s *searcher) CreateIndex() error {
+	return s.CreateIndexContext(context.Background())
+}
+
+// CreateIndexContext is CreateIndex which is cancelled by ctx.
+// requests in flight are cancelled, and a sync is saved to resume on next start.
+func (s *searcher) CreateIndexContext(ctx context.Context) error {
 	color.Cyan("[start] initialize index.")
-	// get user
-	user, reload, err := s.getUser()
-	if err != nil {
+
+	// resolve an account of each source, which keys cached data.
+	for _, src := range s.sources {
+		if err := s.resolveAccount(ctx, src); err != nil {
+			return fmt.Errorf("[err] createIndex %w", err)
+		}
+	}
+
+	// read old database of all sources.
+	newBuckets := make([]bool, len(s.sources))
+	oldStarredLists := make([][]*git.Starred, len(s.sources))
+	docs := map[string]*git.Starred{}
+	for i, src := range s.sources {
+		isNewIndex, oldStarredList, err := s.readStarred(src)
+		if err != nil {
+			return fmt.Errorf("[err] createIndex %w", err)
+		}
+		newBuckets[i] = isNewIndex
+		oldStarredLists[i] = oldStarredList
+		for _, starred := range oldStarredList {
+			docs[docID(src, starred)] = starred
+		}
+	}
+
+	// check whether the index agrees with cached starred or not.
+	if err := s.syncIndex(docs); err != nil {
 		return fmt.Errorf("[err] createIndex %w", err)
 	}
 
+	// refresh starred of each source.
+	for i, src := range s.sources {
+		if err := s.refreshSource(ctx, src, newBuckets[i], oldStarredLists[i]); err != nil {
+			return fmt.Errorf("[err] createIndex %w", err)
+		}
+	}
+
+	// compute vectors of refreshed starred for vector and hybrid modes.
+	if s.vectors {
+		if err := s.syncVectors(ctx); err != nil {
+			return fmt.Errorf("[err] createIndex %w", err)
+		}
+	}
+	return nil
+}
+
+// readStarred returns cached starred of a source and whether a bucket of the source is new or not.
+func (s *searcher) readStarred(src *source) (isNewIndex bool, oldStarredList []*git.Starred, err error) {
 	// check to whether exist starred items or not.
-	var isNewIndex bool
-	if err := s.db.Update(func(tx *bolt.Tx) error {
+	if suberr := s.db.Update(func(tx *bolt.Tx) error {
 		var err error
-		bucket := tx.Bucket([]byte(starredBucketName(s.gitToken)))
+		bucket := tx.Bucket([]byte(starredBucketName(src.key)))
 		if bucket == nil {
-			bucket, err = tx.CreateBucket([]byte(starredBucketName(s.gitToken)))
+			bucket, err = tx.CreateBucket([]byte(starredBucketName(src.key)))
 			if err != nil {
 				return err
 			}
@@ -205,126 +595,130 @@ func (s *searcher) CreateIndex() error {
 			isNewIndex = false
 		}
 		return nil
-	}); err != nil {
+	}); suberr != nil {
 		ClearAll()
 		color.Yellow("[err] collapse db file, so delete db file")
-		return fmt.Errorf("[err] createIndex %w", err)
+		err = fmt.Errorf("[err] readStarred %w", suberr)
+		return
 	}
 
-	// read old database.
-	var oldStarredList []*git.Starred
-	oldStarredMap := map[string]*git.Starred{}
-	if !isNewIndex {
-		// read old starred from db
-		s.db.View(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket([]byte(starredBucketName(s.gitToken)))
-			bucket.ForEach(func(k, v []byte) error {
-				var starred *git.Starred
-				if err := json.Unmarshal(v, &starred); err != nil {
-					color.Yellow("[err] parsing %s", string(k))
-				} else {
-					oldStarredList = append(oldStarredList, starred)
-					oldStarredMap[starred.FullName] = starred
+	if isNewIndex {
+		return
+	}
+
+	// read old starred from db
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(starredBucketName(src.key)))
+		bucket.ForEach(func(k, v []byte) error {
+			var starred *git.Starred
+			if err := json.Unmarshal(v, &starred); err != nil {
+				color.Yellow("[err] parsing %s", string(k))
+			} else {
+				if starred.Provider == "" {
+					starred.Provider = src.git.Provider()
 				}
-				return nil
-			})
+				oldStarredList = append(oldStarredList, starred)
+			}
 			return nil
 		})
+		return nil
+	})
+	return
+}
 
-		// write old starred to index
-		for _, starred := range oldStarredList {
-			if err := s.index.Index(starred.FullName, starred); err != nil {
-				color.Yellow("[err] indexing %s", starred.FullName)
-			}
-		}
+// refreshSource reloads starred of a source if its cache is expired or doesn't exist, or a sync is unfinished.
+func (s *searcher) refreshSource(ctx context.Context, src *source, isNewIndex bool, oldStarredList []*git.Starred) error {
+	host := src.git.Host()
+
+	// get user
+	user, reload, err := s.getUser(ctx, src)
+	if err != nil {
+		return fmt.Errorf("[err] refreshSource %w", err)
+	}
+	state, err := s.loadSyncState(src)
+	if err != nil {
+		return fmt.Errorf("[err] refreshSource %w", err)
 	}
 
 	// are you all ready?
-	if !reload && !isNewIndex {
-		count, _ := s.index.DocCount()
-		color.Green("[success][using cache] %d items", count)
+	if !reload && !isNewIndex && state == nil {
+		color.Green("[success][using cache][%s] %d items", host, len(oldStarredList))
 		return nil
 	}
 
 	// reload new starred list.
-	newStarredList, err := s.git.ListStarredAll()
-	if err != nil {
-		color.Yellow("[err] don't getting starred list %s", err.Error())
-		if !isNewIndex {
-			count, _ := s.index.DocCount()
-			color.Yellow("[fail][using cache] %d items", count)
-			return nil
-		}
-		return fmt.Errorf("[err] CreateIndex %w", err)
+	if isNewIndex && state == nil {
+		color.White("[refresh][%s] all repositories", host)
 	}
-	newStarredMap := map[string]*git.Starred{}
-	for _, starred := range newStarredList {
-		newStarredMap[starred.FullName] = starred
-	}
-
-	// update and insert
-	if isNewIndex {
-		color.White("[refresh] all repositories")
-		s.git.SetReadme(newStarredList)
-		s.writeDBAndIndex(newStarredList)
-	} else {
-		// insert or update starred
-		var insertList []*git.Starred
-		var updateList []*git.Starred
-		for _, newStarred := range newStarredList {
-			if oldStarred, ok := oldStarredMap[newStarred.FullName]; !ok {
-				insertList = append(insertList, newStarred)
-				color.White("[insert] %s repository pushed_at %s",
-					newStarred.FullName, newStarred.PushedAt.Format(time.RFC3339))
-			} else {
-				if oldStarred.PushedAt.Unix() != newStarred.PushedAt.Unix() &&
-					oldStarred.CachedAt.Unix() < time.Now().Add(-24*7*time.Hour).Unix() { // after 7 days.
-					updateList = append(updateList, newStarred)
-					color.White("[update] %s repository pushed_at %s",
-						newStarred.FullName, newStarred.PushedAt.Format(time.RFC3339))
-				}
-			}
+	if err := s.sync(ctx, src, oldStarredList); err != nil {
+		if ctx.Err() != nil {
+			color.Yellow("[stop][%s] sync is saved, it resumes on next start", host)
+			return fmt.Errorf("[err] refreshSource %w", err)
 		}
-
-		// insert
-		s.git.SetReadme(insertList)
-		s.writeDBAndIndex(insertList)
-
-		// update
-		s.git.SetReadme(updateList)
-		s.writeDBAndIndex(updateList)
-
-		// delete starred
-		var deleteList []*git.Starred
-		for _, oldStarred := range oldStarredList {
-			if _, ok := newStarredMap[oldStarred.FullName]; !ok {
-				deleteList = append(deleteList, oldStarred)
-				color.White("[delete] %s repository pushed_at %s",
-					oldStarred.FullName, oldStarred.PushedAt.Format(time.RFC3339))
+		var partial *git.PartialError
+		if errors.As(err, &partial) {
+			// what succeeded is committed, and the rest is fetched on next start.
+			for _, page := range partial.Pages {
+				color.Yellow("[partial][%s] %s", host, page.Error())
 			}
+			for _, repo := range partial.Repos {
+				color.Yellow("[partial][%s] %s", host, repo.Error())
+			}
+			color.Yellow("[partial][%s] %d items, %d pages and %d readme are fetched on next start",
+				host, s.countStarred(src), len(partial.Pages), len(partial.Repos))
+			printQuota(src)
+			return nil
 		}
-		// delete
-		s.deleteDBAndIndex(deleteList)
+		color.Yellow("[err] don't getting starred list %s", err.Error())
+		if errors.Is(err, git.ErrApiQuotaExceed) {
+			color.Yellow("[pause][%s] sync is saved, run findgs again after the api quota is reset", host)
+		}
+		printQuota(src)
+		if !isNewIndex {
+			color.Yellow("[fail][using cache][%s] %d items", host, len(oldStarredList))
+			return nil
+		}
+		return fmt.Errorf("[err] refreshSource %w", err)
 	}
 
 	// rewrite a user to db
 	userData, err := json.Marshal(user)
 	if err != nil {
-		return fmt.Errorf("[err] createIndex %w", err)
+		return fmt.Errorf("[err] refreshSource %w", err)
 	}
 	s.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(userBucketName))
-		bucket.Put([]byte(s.gitToken), userData)
+		bucket.Put([]byte(src.key), userData)
 		return nil
 	})
 
-	count, _ := s.index.DocCount()
-	color.Green("[success][new reload] %d items", count)
+	color.Green("[success][new reload][%s] %d items", host, s.countStarred(src))
+	printQuota(src)
 	return nil
 }
 
+// countStarred returns a count of cached starred of a source.
+func (s *searcher) countStarred(src *source) int {
+	total := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(starredBucketName(src.key))); bucket != nil {
+			total = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	return total
+}
+
+// printQuota prints a remaining api quota of a source if a provider tells it.
+func printQuota(src *source) {
+	if limit := src.git.RateLimit(); limit.Limit != 0 {
+		color.White("[quota][%s] %d/%d remains, reset at %s",
+			src.git.Host(), limit.Remaining, limit.Limit, limit.Reset.Format(time.Kitchen))
+	}
+}
+
 // getUserInfo returns a user information and reload flag.
-func (s *searcher) getUser() (user *git.User, reload bool, err error) {
+func (s *searcher) getUser(ctx context.Context, src *source) (user *git.User, reload bool, err error) {
 	// read a user from database.
 	var userData []byte
 	suberr := s.db.Update(func(tx *bolt.Tx) error {
@@ -336,7 +730,7 @@ func (s *searcher) getUser() (user *git.User, reload bool, err error) {
 				return inerr
 			}
 		}
-		userData = bucket.Get([]byte(s.gitToken))
+		userData = bucket.Get([]byte(src.key))
 		return nil
 	})
 	if suberr != nil { // maybe collapse db file.
@@ -348,7 +742,7 @@ func (s *searcher) getUser() (user *git.User, reload bool, err error) {
 
 	// if a user doesn't exist.
 	if userData == nil || len(userData) == 0 {
-		newUser, suberr := s.git.User()
+		newUser, suberr := src.git.UserContext(ctx)
 		if suberr != nil {
 			err = fmt.Errorf("[err] createIndex %w", suberr)
 			return
@@ -364,7 +758,7 @@ func (s *searcher) getUser() (user *git.User, reload bool, err error) {
 		color.Red("[err] retry again!")
 		s.db.Update(func(tx *bolt.Tx) error {
 			b := tx.Bucket([]byte(userBucketName))
-			b.Delete([]byte(s.gitToken))
+			b.Delete([]byte(src.key))
 			return nil
 		})
 		err = fmt.Errorf("[err] createIndex %w", suberr)
@@ -374,7 +768,7 @@ func (s *searcher) getUser() (user *git.User, reload bool, err error) {
 	// check whether reload or not.
 	if user.CachedAt.Unix() < time.Now().Add(-1*time.Hour).Unix() {
 		reload = true
-		newUser, suberr := s.git.User()
+		newUser, suberr := src.git.UserContext(ctx)
 		if suberr != nil {
 			color.Yellow("[err] a user doesn't reload %s", suberr.Error())
 		} else {
@@ -384,13 +778,13 @@ func (s *searcher) getUser() (user *git.User, reload bool, err error) {
 	return
 }
 
-func (s *searcher) writeDBAndIndex(starredList []*git.Starred) error {
+func (s *searcher) writeDBAndIndex(src *source, starredList []*git.Starred) error {
 	if len(starredList) == 0 {
 		return nil
 	}
 	// write db
 	s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(starredBucketName(s.gitToken)))
+		bucket := tx.Bucket([]byte(starredBucketName(src.key)))
 		for _, starred := range starredList {
 			if starred.Error != nil {
 				color.Yellow("[err][db write] don't found readme data %s", starred.FullName)
@@ -409,38 +803,151 @@ func (s *searcher) writeDBAndIndex(starredList []*git.Starred) error {
 		return nil
 	})
 	// write index
+	if err := s.markIndexDirty(true); err != nil {
+		return fmt.Errorf("[err] writeDBAndIndex %w", err)
+	}
+	batch := s.index.NewBatch()
 	for _, starred := range starredList {
 		if starred.Error != nil {
 			color.Yellow("[err][index write] don't found readme data %s", starred.FullName)
 			continue
 		}
-		if err := s.index.Index(starred.FullName, starred); err != nil {
+		if err := batch.Index(docID(src, starred), newDocument(starred)); err != nil {
 			color.Yellow("[err][index write] don't put %s", starred.FullName)
 			continue
 		}
 	}
-	return nil
+	if err := s.index.Batch(batch); err != nil {
+		return fmt.Errorf("[err] writeDBAndIndex %w", err)
+	}
+	return s.markIndexDirty(false)
 }
 
-func (s *searcher) deleteDBAndIndex(starredList []*git.Starred) error {
+func (s *searcher) deleteDBAndIndex(src *source, starredList []*git.Starred) error {
 	if len(starredList) == 0 {
 		return nil
 	}
 	// delete db
 	s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(starredBucketName(s.gitToken)))
+		bucket := tx.Bucket([]byte(starredBucketName(src.key)))
 		for _, starred := range starredList {
 			bucket.Delete([]byte(starred.FullName))
 		}
 		return nil
 	})
 	// delete index
+	if err := s.markIndexDirty(true); err != nil {
+		return fmt.Errorf("[err] deleteDBAndIndex %w", err)
+	}
+	batch := s.index.NewBatch()
 	for _, starred := range starredList {
-		s.index.Delete(starred.FullName)
+		batch.Delete(docID(src, starred))
+	}
+	if err := s.index.Batch(batch); err != nil {
+		return fmt.Errorf("[err] deleteDBAndIndex %w", err)
+	}
+	return s.markIndexDirty(false)
+}
+
+// syncIndex rebuilds the index from cached starred if they disagree.
+func (s *searcher) syncIndex(docs map[string]*git.Starred) error {
+	if s.isIndexSynced(len(docs)) {
+		return nil
+	}
+
+	color.Yellow("[rebuild] index doesn't match cached data, so rebuild index")
+	if err := s.index.Close(); err != nil {
+		color.Yellow("[err] close index %s", err.Error())
+	}
+	if err := os.RemoveAll(s.indexPath); err != nil {
+		return fmt.Errorf("[err] syncIndex %w", err)
+	}
+	index, err := bleve.New(s.indexPath, newIndexMapping())
+	if err != nil {
+		return fmt.Errorf("[err] syncIndex %w", err)
+	}
+	s.index = index
+
+	batch := s.index.NewBatch()
+	batch.SetInternal(indexVersionKey, []byte(indexVersion))
+	batch.SetInternal(indexOwnerKey, []byte(s.indexOwner()))
+	for id, starred := range docs {
+		if err := batch.Index(id, newDocument(starred)); err != nil {
+			color.Yellow("[err] indexing %s", starred.FullName)
+		}
+	}
+	if err := s.index.Batch(batch); err != nil {
+		return fmt.Errorf("[err] syncIndex %w", err)
 	}
 	return nil
 }
 
-func starredBucketName(token string) string {
-	return token + "_" + starredBucketSuffix
+// isIndexSynced returns whether the index was completely written with the current version and owner.
+func (s *searcher) isIndexSynced(count int) bool {
+	version, err := s.index.GetInternal(indexVersionKey)
+	if err != nil || string(version) != indexVersion {
+		return false
+	}
+	owner, err := s.index.GetInternal(indexOwnerKey)
+	if err != nil || string(owner) != s.indexOwner() {
+		return false
+	}
+	dirty, err := s.index.GetInternal(indexDirtyKey)
+	if err != nil || len(dirty) != 0 {
+		return false
+	}
+	total, err := s.index.DocCount()
+	if err != nil || int(total) != count {
+		return false
+	}
+	return true
+}
+
+// markIndexDirty marks the index while writing, so that an interrupted writing is detected on next start.
+func (s *searcher) markIndexDirty(dirty bool) error {
+	if dirty {
+		return s.index.SetInternal(indexDirtyKey, []byte("1"))
+	}
+	return s.index.DeleteInternal(indexDirtyKey)
+}
+
+// indexOwner returns an identity of sources which the index is made from.
+func (s *searcher) indexOwner() string {
+	var names []string
+	for _, src := range s.sources {
+		names = append(names, starredBucketName(src.key))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// sourceOf returns a source and a db key of a document id.
+func (s *searcher) sourceOf(id string) (*source, string) {
+	ix := strings.Index(id, "/")
+	if ix < 0 {
+		return nil, ""
+	}
+	for _, src := range s.sources {
+		if src.git.Host() == id[:ix] {
+			return src, id[ix+1:]
+		}
+	}
+	return nil, ""
+}
+
+// newSourceGit returns a git client of a source.
+func newSourceGit(src *Source) (git.Git, error) {
+	if src.Provider == git.ProviderGithub && src.API == git.APIGraphQL {
+		return git.NewGithubGraphQL(src.BaseURL, src.Token)
+	}
+	return git.NewProviderGit(src.Provider, src.BaseURL, src.Token)
+}
+
+// docID returns a document id of starred in the index, which is unique across sources.
+func docID(src *source, starred *git.Starred) string {
+	return src.git.Host() + "/" + starred.FullName
+}
+
+func starredBucketName(key string) string {
+	return key + "_" + starredBucketSuffix
 }
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 
 	"log"
@@ -43,18 +44,43 @@ func TestNewSearcher(t *testing.T) {
 	assert := assert.New(t)
 
 	tests := map[string]struct {
-		token string
-		isErr bool
+		token  string
+		others []*Source
+		isErr  bool
 	}{
 		"fail":    {token: "", isErr: true},
 		"success": {token: "fake-token"},
+		"sources": {token: "fake-token", others: []*Source{
+			{Provider: git.ProviderGitlab, Token: "fake-gitlab-token"},
+			{Provider: git.ProviderGitea, BaseURL: "https://codeberg.org", Token: "fake-gitea-token"},
+		}},
+		"github enterprise": {token: "fake-token", others: []*Source{
+			{Provider: git.ProviderGithub, BaseURL: "https://github.example.com", Token: "fake-enterprise-token"},
+		}},
+		"github graphql": {token: "fake-token", others: []*Source{
+			{Provider: git.ProviderGithub, BaseURL: "https://github.example.com", Token: "fake-enterprise-token", API: git.APIGraphQL},
+		}},
+		"empty source token": {token: "fake-token", others: []*Source{{Provider: git.ProviderGitlab}}, isErr: true},
+		"unknown provider":   {token: "fake-token", others: []*Source{{Provider: "svn", Token: "fake"}}, isErr: true},
+		"duplicated host": {token: "fake-token", others: []*Source{
+			{Provider: git.ProviderGitlab, Token: "fake-gitlab-token"},
+			{Provider: git.ProviderGitlab, BaseURL: "https://gitlab.com/", Token: "other-gitlab-token"},
+		}, isErr: true},
 	}
 
-	for _, t := range tests {
-		s, err := NewSearcher(t.token)
-		assert.Equal(t.isErr, err != nil)
+	for name, t := range tests {
+		s, err := NewSearcher(t.token, t.others...)
+		assert.Equal(t.isErr, err != nil, name)
 		if err == nil {
-			s.(*searcher).db.Close()
+			assert.Len(s.(*searcher).sources, len(t.others)+1, name)
+			for _, src := range s.(*searcher).sources {
+				assert.NotEmpty(src.token, name)
+				assert.Empty(src.key, name)
+				found, key := s.(*searcher).sourceOf(docID(src, &git.Starred{FullName: "allan/hello"}))
+				assert.Equal(src, found, name)
+				assert.Equal("allan/hello", key, name)
+			}
+			s.Close()
 		}
 	}
 }
@@ -64,7 +90,7 @@ func TestSearcher_TotalDoc(t *testing.T) {
 	token := os.Getenv("GITHUB_TOKEN")
 	s, err := NewSearcher(token)
 	assert.NoError(err)
-	defer s.(*searcher).db.Close()
+	defer s.Close()
 	err = s.CreateIndex()
 	assert.NoError(err)
 
@@ -86,7 +112,7 @@ func TestSearcher_Search(t *testing.T) {
 	token := os.Getenv("GITHUB_TOKEN")
 	s, err := NewSearcher(token)
 	assert.NoError(err)
-	defer s.(*searcher).db.Close()
+	defer s.Close()
 	err = s.CreateIndex()
 	assert.NoError(err)
 
@@ -118,7 +144,7 @@ func TestSearcher_CreateIndex(t *testing.T) {
 	token := os.Getenv("GITHUB_TOKEN")
 	s, err := NewSearcher(token)
 	assert.NoError(err)
-	defer s.(*searcher).db.Close()
+	defer s.Close()
 
 	tests := map[string]struct {
 		isErr bool
@@ -145,8 +171,9 @@ func TestGetUser(t *testing.T) {
 	token := os.Getenv("GITHUB_TOKEN")
 	s, err := NewSearcher(token)
 	assert.NoError(err)
-	defer s.(*searcher).db.Close()
-	user, err := s.(*searcher).git.User()
+	defer s.Close()
+	assert.NoError(s.(*searcher).resolveAccount(context.Background(), s.(*searcher).sources[0]))
+	user, err := s.(*searcher).sources[0].git.UserContext(context.Background())
 	assert.NoError(err)
 
 	tests := map[string]struct {
@@ -159,28 +186,28 @@ func TestGetUser(t *testing.T) {
 	for k, t := range tests {
 		switch k {
 		case "reload":
-			user.CachedAt = git.JsonTime{time.Now().Add(-2 * time.Hour)}
+			user.CachedAt = git.JsonTime{Time: time.Now().Add(-2 * time.Hour)}
 			userData, err := json.Marshal(user)
 			assert.NoError(err)
 			s.(*searcher).db.Update(func(tx *bolt.Tx) error {
 				bucket := tx.Bucket([]byte(userBucketName))
-				bucket.Put([]byte(s.(*searcher).gitToken), userData)
+				bucket.Put([]byte(s.(*searcher).sources[0].key), userData)
 				return nil
 			})
-			result, reload, err := s.(*searcher).getUser()
+			result, reload, err := s.(*searcher).getUser(context.Background(), s.(*searcher).sources[0])
 			assert.NotEmpty(result)
 			assert.NoError(err)
 			assert.Equal(reload, t.reload)
 		case "not reload":
-			user.CachedAt = git.JsonTime{time.Now().Add(1 * time.Hour)}
+			user.CachedAt = git.JsonTime{Time: time.Now().Add(1 * time.Hour)}
 			userData, err := json.Marshal(user)
 			assert.NoError(err)
 			s.(*searcher).db.Update(func(tx *bolt.Tx) error {
 				bucket := tx.Bucket([]byte(userBucketName))
-				bucket.Put([]byte(s.(*searcher).gitToken), userData)
+				bucket.Put([]byte(s.(*searcher).sources[0].key), userData)
 				return nil
 			})
-			result, reload, err := s.(*searcher).getUser()
+			result, reload, err := s.(*searcher).getUser(context.Background(), s.(*searcher).sources[0])
 			assert.NotEmpty(result)
 			assert.NoError(err)
 			assert.Equal(reload, t.reload)
@@ -195,7 +222,8 @@ func TestCRUD_DBAndIndex(t *testing.T) {
 	token := os.Getenv("GITHUB_TOKEN")
 	s, err := NewSearcher(token)
 	assert.NoError(err)
-	defer s.(*searcher).db.Close()
+	defer s.Close()
+	assert.NoError(s.(*searcher).resolveAccount(context.Background(), s.(*searcher).sources[0]))
 
 	// write and delete
 	tests := map[string]struct {
@@ -208,19 +236,19 @@ func TestCRUD_DBAndIndex(t *testing.T) {
 	}
 
 	for _, t := range tests {
-		err := s.(*searcher).writeDBAndIndex(t.starredList)
+		err := s.(*searcher).writeDBAndIndex(s.(*searcher).sources[0], t.starredList)
 		assert.Equal(t.isErr, err != nil)
 		s.(*searcher).db.View(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket([]byte(starredBucketName(s.(*searcher).gitToken)))
+			bucket := tx.Bucket([]byte(starredBucketName(s.(*searcher).sources[0].key)))
 			data := bucket.Get([]byte(t.starredList[0].FullName))
 			assert.NotEmpty(data)
 			return nil
 		})
 
-		err = s.(*searcher).deleteDBAndIndex(t.starredList)
+		err = s.(*searcher).deleteDBAndIndex(s.(*searcher).sources[0], t.starredList)
 		assert.Equal(t.isErr, err != nil)
 		s.(*searcher).db.View(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket([]byte(starredBucketName(s.(*searcher).gitToken)))
+			bucket := tx.Bucket([]byte(starredBucketName(s.(*searcher).sources[0].key)))
 			data := bucket.Get([]byte(t.starredList[0].FullName))
 			assert.Empty(data)
 			return nil
@@ -234,3 +262,145 @@ func TestMain(m *testing.M) {
 		os.Exit(m.Run())
 	}
 }
+
+func TestSearcher_SyncIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	indexPath := filepath.Join(dir, indexDirName)
+	index, err := openIndex(indexPath)
+	assert.NoError(err)
+	g, err := git.NewGit("fake-token")
+	assert.NoError(err)
+	src := &source{key: "fake-token", git: g}
+	s := &searcher{sources: []*source{src}, index: index, indexPath: indexPath}
+	defer func() { s.index.Close() }()
+
+	starredList := map[string]*git.Starred{}
+	for _, starred := range []*git.Starred{
+		{Owner: "allan", Repo: "hello", FullName: "allan/hello"},
+		{Owner: "allan", Repo: "world", FullName: "allan/world"},
+	} {
+		starredList[docID(src, starred)] = starred
+	}
+
+	tests := map[string]struct {
+		prepare func()
+		synced  bool
+	}{
+		"new index":   {prepare: func() {}, synced: false},
+		"synced":      {prepare: func() { s.syncIndex(starredList) }, synced: true},
+		"dirty":       {prepare: func() { s.markIndexDirty(true) }, synced: false},
+		"rebuilt":     {prepare: func() { s.syncIndex(starredList) }, synced: true},
+		"other owner": {prepare: func() { s.index.SetInternal(indexOwnerKey, []byte("other")) }, synced: false},
+		"disagree":    {prepare: func() { s.syncIndex(starredList); s.index.Delete("github.com/allan/hello") }, synced: false},
+	}
+
+	for _, name := range []string{"new index", "synced", "dirty", "rebuilt", "other owner", "disagree"} {
+		t := tests[name]
+		t.prepare()
+		assert.Equal(t.synced, s.isIndexSynced(len(starredList)), name)
+	}
+
+	// reopen a persisted index.
+	assert.NoError(s.syncIndex(starredList))
+	assert.NoError(s.index.Close())
+	s.index, err = openIndex(indexPath)
+	assert.NoError(err)
+	assert.True(s.isIndexSynced(len(starredList)))
+
+	// a collapsed index is made again.
+	assert.NoError(s.index.Close())
+	assert.NoError(os.RemoveAll(filepath.Join(indexPath, "index_meta.json")))
+	s.index, err = openIndex(indexPath)
+	assert.NoError(err)
+	assert.False(s.isIndexSynced(len(starredList)))
+}
+
+func TestSearcher_NormalizeScore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.writeDBAndIndex(src, []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Description: "cli cli", Readme: "cobra is a cli library"},
+		{Owner: "urfave", Repo: "cli", FullName: "urfave/cli", Description: "simple package", Readme: "cli apps in go"},
+		{Owner: "allan", Repo: "cake", FullName: "allan/cake", Description: "cake recipes"},
+	}))
+
+	// scores don't depend on other matches, so that a better match is scored higher but below 1.
+	found, err := s.Search("cli", 0)
+	assert.NoError(err)
+	assert.Len(found, 2)
+	for _, result := range found {
+		assert.True(result.Score > 0 && result.Score < 1, result.FullName)
+		assert.Equal(normalizeScore(result.RawScore), result.Score, result.FullName)
+	}
+	assert.Greater(found[0].Score, found[1].Score)
+
+	// a minimum score is compared with normalized scores.
+	filtered, err := s.Search("cli", found[0].Score)
+	assert.NoError(err)
+	assert.Len(filtered, 1)
+}
+
+func TestSearcher_SearchFuzzy(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.writeDBAndIndex(src, []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Readme: "readme of cobra"},
+		{Owner: "spf13", Repo: "viper", FullName: "spf13/viper", Readme: "readme of viper"},
+		{Owner: "spf13", Repo: "afero", FullName: "spf13/afero", Readme: "readme of afero"},
+		{Owner: "urfave", Repo: "cli", FullName: "urfave/cli", Readme: "raedme of cli"},
+		{Owner: "allan", Repo: "cake", FullName: "allan/cake", Readme: "recipes"},
+	}))
+
+	// a typo is matched fuzzily if few repositories are matched exactly.
+	found, err := s.Search("raedme", 0)
+	assert.NoError(err)
+	assert.Len(found, 4)
+	fuzzy := map[string]bool{}
+	for _, result := range found {
+		fuzzy[result.FullName] = result.Fuzzy
+	}
+	assert.Equal(map[string]bool{"spf13/cobra": true, "spf13/viper": true, "spf13/afero": true, "urfave/cli": false}, fuzzy)
+
+	// a fuzzy search isn't tried if enough repositories are matched exactly.
+	found, err = s.Search("readme", 0)
+	assert.NoError(err)
+	assert.Len(found, 3)
+	for _, result := range found {
+		assert.False(result.Fuzzy, result.FullName)
+	}
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+)
+
+const (
+	// maxSalientTerms is a count of terms of README which a similar query is made from.
+	maxSalientTerms = 20
+	// minSalientTermLength is a length of a term, under which a term isn't salient such as v1 and js.
+	minSalientTermLength = 3
+)
+
+var (
+	ErrNotFoundRepository = errors.New("[err] Not found repository")
+)
+
+// Similar searches repositories similar to a starred repository whose full name is such as spf13/cobra.
+// a query is made from its topics, description and salient terms of README by TF-IDF against the index,
+// and the repository itself isn't found.
+func (s *searcher) Similar(ctx context.Context, fullName string, opts *SearchOptions) (*Page, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("[err] Similar %w", ErrInvalidParam)
+	}
+
+	id, starred := s.findStarred(fullName)
+	if starred == nil {
+		return nil, fmt.Errorf("[err] Similar %w %s", ErrNotFoundRepository, fullName)
+	}
+	q, err := s.similarQuery(starred)
+	if err != nil {
+		return nil, fmt.Errorf("[err] Similar %w", err)
+	}
+	if q == nil {
+		return &Page{Results: []*Result{}, Facets: map[string][]*FacetCount{}, Offset: opts.Offset}, nil
+	}
+
+	excluded := bleve.NewBooleanQuery()
+	excluded.AddMust(q)
+	excluded.AddMustNot(bleve.NewDocIDQuery([]string{id}))
+	searchResult, err := s.searchQuery(ctx, excluded, opts)
+	if err != nil {
+		return nil, fmt.Errorf("[err] Similar %w", err)
+	}
+	return s.newPage(ctx, searchResult, opts, nil, ""), nil
+}
+
+// findStarred returns cached starred of a full name with its document id, which is nil if it isn't found.
+func (s *searcher) findStarred(fullName string) (string, *git.Starred) {
+	var id string
+	var starred *git.Starred
+	s.db.View(func(tx *bolt.Tx) error {
+		for _, src := range s.sources {
+			bucket := tx.Bucket([]byte(starredBucketName(src.key)))
+			if bucket == nil {
+				continue
+			}
+			data := bucket.Get([]byte(fullName))
+			if data == nil {
+				continue
+			}
+			if err := json.Unmarshal(data, &starred); err == nil {
+				id = docID(src, starred)
+				return nil
+			}
+		}
+		return nil
+	})
+	return id, starred
+}
+
+// similarQuery returns a query of topics, a description and salient terms of README, which are boosted as fields.
+// it returns nil if there is nothing to match.
+func (s *searcher) similarQuery(starred *git.Starred) (query.Query, error) {
+	var disjuncts []query.Query
+	for _, topic := range starred.Topics {
+		q := bleve.NewTermQuery(strings.ToLower(topic))
+		q.SetField(textFields["topic"])
+		q.SetBoost(fieldBoosts["topic"])
+		disjuncts = append(disjuncts, q)
+	}
+	if starred.Description != "" {
+		q := bleve.NewMatchQuery(starred.Description)
+		q.SetField(textFields["desc"])
+		q.SetBoost(fieldBoosts["desc"])
+		disjuncts = append(disjuncts, q)
+	}
+	terms, err := s.salientTerms(parseMarkdown(starred.Readme).Prose, maxSalientTerms)
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range terms {
+		q := bleve.NewTermQuery(term)
+		q.SetField(textFields["readme"])
+		q.SetBoost(fieldBoosts["readme"])
+		disjuncts = append(disjuncts, q)
+	}
+	if len(disjuncts) == 0 {
+		return nil, nil
+	}
+	return bleve.NewDisjunctionQuery(disjuncts...), nil
+}
+
+// salientTerms returns at most n terms of prose of README in order of TF-IDF, where document frequencies are of the index.
+// terms are analyzed as prose of README is indexed.
+func (s *searcher) salientTerms(readme string, n int) ([]string, error) {
+	analyzer := s.index.Mapping().AnalyzerNamed(markdownAnalyzer)
+	if analyzer == nil || readme == "" {
+		return nil, nil
+	}
+	frequencies := map[string]int{}
+	for _, token := range analyzer.Analyze([]byte(readme)) {
+		if term := string(token.Term); len([]rune(term)) >= minSalientTermLength {
+			frequencies[term]++
+		}
+	}
+
+	total, err := s.index.DocCount()
+	if err != nil {
+		return nil, err
+	}
+	weights := map[string]float64{}
+	var terms []string
+	for term, frequency := range frequencies {
+		count, err := s.docFrequency(textFields["readme"], term)
+		if err != nil {
+			return nil, err
+		}
+		// a term which every repository has isn't salient.
+		idf := math.Log(float64(total+1) / float64(count+1))
+		if idf <= 0 {
+			continue
+		}
+		weights[term] = float64(frequency) * idf
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if weights[terms[i]] != weights[terms[j]] {
+			return weights[terms[i]] > weights[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms, nil
+}
+
+// docFrequency returns a count of documents which have a term in a field.
+func (s *searcher) docFrequency(field, term string) (uint64, error) {
+	dict, err := s.index.FieldDictRange(field, []byte(term), []byte(term))
+	if err != nil {
+		return 0, err
+	}
+	defer dict.Close()
+
+	entry, err := dict.Next()
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return entry.Count, nil
+}
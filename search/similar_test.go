@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearcher_Similar(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := openIndex(filepath.Join(dir, indexDirName))
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+
+	docs := []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Description: "A Commander for modern Go CLI interactions",
+			Topics: []string{"cli", "go"}, Readme: "Cobra is a library for creating powerful modern CLI applications with subcommands and flags."},
+		{Owner: "urfave", Repo: "cli", FullName: "urfave/cli", Description: "A simple, fast, and fun package for building command line apps in Go",
+			Topics: []string{"cli", "go"}, Readme: "cli is a package for building command line applications with subcommands and flags."},
+		{Owner: "spf13", Repo: "viper", FullName: "spf13/viper", Description: "Go configuration with fangs",
+			Topics: []string{"config", "go"}, Readme: "Viper is a complete configuration solution for Go applications."},
+		{Owner: "allan", Repo: "cake", FullName: "allan/cake", Description: "cake recipes",
+			Readme: "Recipes of chocolate cake."},
+	}
+	assert.NoError(s.writeDBAndIndex(src, docs))
+
+	// salient terms of README are rare in the index.
+	terms, err := s.salientTerms(docs[0].Readme, 3)
+	assert.NoError(err)
+	assert.Len(terms, 3)
+	assert.NotContains(terms, "applic")
+
+	tests := map[string]struct {
+		input  string
+		output []string
+		isErr  bool
+	}{
+		"cli":      {input: "spf13/cobra", output: []string{"urfave/cli", "spf13/viper"}},
+		"config":   {input: "spf13/viper", output: []string{"spf13/cobra", "urfave/cli"}},
+		"unknown":  {input: "allan/unknown", isErr: true},
+		"not like": {input: "allan/cake", output: []string{}},
+	}
+
+	for name, t := range tests {
+		page, err := s.Similar(context.Background(), t.input, &SearchOptions{})
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			assert.True(errors.Is(err, ErrNotFoundRepository), name)
+			continue
+		}
+		names := []string{}
+		for _, found := range page.Results {
+			names = append(names, found.FullName)
+		}
+		assert.Equal(t.output, names, name)
+		if len(page.Results) != 0 {
+			assert.True(page.Results[0].Score > 0 && page.Results[0].Score < 1, name)
+		}
+	}
+}
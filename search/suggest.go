@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	bleve_search "github.com/blevesearch/bleve/search"
+)
+
+const (
+	// DefaultFuzziness is an edit distance of words, which are matched fuzzily when an exact search finds few repositories.
+	DefaultFuzziness = 2
+	// maxFuzziness is the maximum edit distance which bleve supports.
+	maxFuzziness = 2
+
+	// minExactHits is a count of hits of an exact search, under which a fuzzy search is tried.
+	minExactHits = 3
+	// minSuggestLength is a length of a word, under which a word isn't corrected.
+	minSuggestLength = 3
+
+	// wordsFieldName is a field of lowercase words of a name, a description and readme, which are a dictionary of suggestions.
+	wordsFieldName = "words"
+)
+
+var (
+	fuzziness = DefaultFuzziness
+
+	// plainWord matches a word without a field, an operator and a wildcard.
+	plainWord = regexp.MustCompile(`^[\p{L}\p{N}]+$`)
+)
+
+// SetFuzziness sets an edit distance of a fuzzy search between 0 and 2.
+// a fuzzy search isn't tried if it's zero.
+func SetFuzziness(n int) error {
+	if n < 0 || n > maxFuzziness {
+		return fmt.Errorf("[err] SetFuzziness %w fuzziness %d", ErrInvalidParam, n)
+	}
+	fuzziness = n
+	return nil
+}
+
+// Suggest returns a searching text whose words are corrected by words in the index, such as "search engine" for "serach engin".
+// it returns an empty string if there is nothing to correct.
+func (s *searcher) Suggest(ctx context.Context, text string) (string, error) {
+	words := strings.Fields(text)
+	corrected := false
+	for i, word := range words {
+		if !isSuggestable(word) {
+			continue
+		}
+		suggestion, err := s.suggestWord(ctx, strings.ToLower(word))
+		if err != nil {
+			return "", fmt.Errorf("[err] Suggest %w", err)
+		}
+		if suggestion != "" && suggestion != strings.ToLower(word) {
+			words[i] = suggestion
+			corrected = true
+		}
+	}
+	if !corrected {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// suggestWord returns the nearest word in the index, which is the most frequent one among words of the same distance.
+// it returns an empty string if no word is near.
+// only words starting with the same first letter are scanned, because a mistyped first letter is rare and scanning all words is slow.
+func (s *searcher) suggestWord(ctx context.Context, word string) (string, error) {
+	first, _ := utf8.DecodeRuneInString(word)
+	dict, err := s.index.FieldDictPrefix(wordsFieldName, []byte(string(first)))
+	if err != nil {
+		return "", err
+	}
+	defer dict.Close()
+
+	maxDistance := suggestDistance(word)
+	var best string
+	var bestCount uint64
+	bestDistance := maxDistance + 1
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return "", err
+		}
+		if entry == nil {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if entry.Term == word {
+			return word, nil
+		}
+		distance, exceeded := bleve_search.LevenshteinDistanceMax(word, entry.Term, maxDistance)
+		if exceeded || distance > maxDistance {
+			continue
+		}
+		if distance < bestDistance || (distance == bestDistance && entry.Count > bestCount) {
+			best, bestCount, bestDistance = entry.Term, entry.Count, distance
+		}
+	}
+	return best, nil
+}
+
+// suggestDistance returns an edit distance which a word is corrected within, which is shorter for a short word.
+func suggestDistance(word string) int {
+	if utf8.RuneCountInString(word) <= 4 {
+		return 1
+	}
+	return maxFuzziness
+}
+
+// isSuggestable returns whether a word of a searching text is corrected or not.
+// fields, phrases, wildcards, operators and short words are kept as they are.
+func isSuggestable(word string) bool {
+	if word == "AND" || word == "OR" || word == "NOT" {
+		return false
+	}
+	return utf8.RuneCountInString(word) >= minSuggestLength && plainWord.MatchString(word)
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blevesearch/bleve"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearcher_Suggest(t *testing.T) {
+	assert := assert.New(t)
+
+	index, err := bleve.NewMemOnly(newIndexMapping())
+	assert.NoError(err)
+	defer index.Close()
+
+	docs := []*git.Starred{
+		{Owner: "blevesearch", Repo: "bleve", FullName: "blevesearch/bleve", Description: "A modern text indexing library",
+			Readme: "bleve is a full-text search engine for go"},
+		{Owner: "meilisearch", Repo: "meilisearch", FullName: "meilisearch/meilisearch", Description: "A lightning-fast search engine",
+			Readme: "search engines are fast"},
+	}
+	for _, doc := range docs {
+		assert.NoError(index.Index(doc.FullName, newDocument(doc)))
+	}
+	s := &searcher{index: index}
+
+	tests := map[string]struct {
+		input  string
+		output string
+	}{
+		"typo":      {input: "serach engin", output: "search engine"},
+		"correct":   {input: "search engine", output: ""},
+		"unknown":   {input: "kubernetes", output: ""},
+		"short":     {input: "go serach", output: "go search"},
+		"syntax":    {input: "serach AND lang:rust stars:>10 indx*", output: "search AND lang:rust stars:>10 indx*"},
+		"uppercase": {input: "Lightnin", output: "lightning"},
+		"first":     {input: "wearch", output: ""},
+	}
+
+	for name, t := range tests {
+		suggestion, err := s.Suggest(context.Background(), t.input)
+		assert.NoError(err, name)
+		assert.Equal(t.output, suggestion, name)
+	}
+
+	// a typo is matched fuzzily.
+	for fuzziness, total := range map[int]uint64{0: 0, 2: 2} {
+		q, err := parseQuery("serach", fuzziness)
+		assert.NoError(err)
+		result, err := index.Search(bleve.NewSearchRequest(q))
+		assert.NoError(err)
+		assert.Equal(total, result.Total, fuzziness)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Suggest(ctx, "serach")
+	assert.Error(err)
+}
+
+func TestSetFuzziness(t *testing.T) {
+	assert := assert.New(t)
+	defer SetFuzziness(DefaultFuzziness)
+
+	tests := map[string]struct {
+		input int
+		isErr bool
+	}{
+		"off":      {input: 0},
+		"max":      {input: 2},
+		"negative": {input: -1, isErr: true},
+		"over":     {input: 3, isErr: true},
+	}
+
+	for name, t := range tests {
+		err := SetFuzziness(t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err == nil {
+			assert.Equal(t.input, fuzziness, name)
+		}
+	}
+}
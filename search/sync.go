@@ -0,0 +1,393 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/git"
+)
+
+const (
+	syncBucketName   = "sync"
+	syncBucketSuffix = "sync"
+
+	// readmeChunkSize is a count of readme which are fetched between checkpoints.
+	readmeChunkSize = 100
+	// concurrentPages is a count of numbered pages which are fetched concurrently between checkpoints.
+	concurrentPages = 5
+	// syncExpiration is a time after which an unfinished sync starts over.
+	syncExpiration = 24 * time.Hour
+)
+
+// syncState is progress of reloading starred of a source, which is persisted until it's finished.
+// starred of fetched pages are staged in a separate bucket.
+type syncState struct {
+	Cursor    string       `json:"cursor"`  // a cursor of a next page.
+	Fetched   bool         `json:"fetched"` // whether all of pages are fetched.
+	Listed    bool         `json:"listed"`
+	Pending   []string     `json:"pending"` // full names whose readme isn't fetched yet.
+	StartedAt git.JsonTime `json:"started_at"`
+}
+
+// sync reloads starred of a source with checkpoints, so that an interrupted or quota-limited sync resumes on next start.
+func (s *searcher) sync(ctx context.Context, src *source, oldStarredList []*git.Starred) error {
+	host := src.git.Host()
+
+	state, err := s.loadSyncState(src)
+	if err != nil {
+		return fmt.Errorf("[err] sync %w", err)
+	}
+	if state == nil || state.StartedAt.Before(time.Now().Add(-syncExpiration)) {
+		state = &syncState{StartedAt: git.JsonTime{Time: time.Now()}}
+		if err := s.resetSync(src, state); err != nil {
+			return fmt.Errorf("[err] sync %w", err)
+		}
+	} else {
+		color.White("[resume][%s] from page %q, %d readme pending", host, state.Cursor, len(state.Pending))
+	}
+
+	// fetch pages of starred.
+	// pages failed after retries are fetched again on next start, and starred of fetched pages are written before them.
+	partial := &git.PartialError{}
+	for !state.Fetched {
+		starredList, next, err := listStarredPages(ctx, src.git, state.Cursor)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, git.ErrApiQuotaExceed) {
+				return fmt.Errorf("[err] sync page %q %w", state.Cursor, err)
+			}
+			partial.Pages = append(partial.Pages, &git.PageError{Cursor: state.Cursor, Err: err})
+			break
+		}
+		state.Cursor, state.Fetched = next, next == ""
+		if err := s.checkpoint(src, state, starredList); err != nil {
+			return fmt.Errorf("[err] sync %w", err)
+		}
+	}
+
+	staged, err := s.stagedStarred(src)
+	if err != nil {
+		return fmt.Errorf("[err] sync %w", err)
+	}
+	oldStarredMap := map[string]*git.Starred{}
+	for _, starred := range oldStarredList {
+		oldStarredMap[starred.FullName] = starred
+	}
+
+	// decide starred which should be inserted or updated.
+	// it's decided again when pages are fetched after a failed page.
+	// cached starred without a language breakdown are fetched again, if languages are enabled.
+	if !state.Listed {
+		pending := map[string]bool{}
+		for _, fullName := range state.Pending {
+			pending[fullName] = true
+		}
+		for _, newStarred := range staged {
+			if pending[newStarred.FullName] {
+				continue
+			}
+			if oldStarred, ok := oldStarredMap[newStarred.FullName]; !ok {
+				pending[newStarred.FullName] = true
+				state.Pending = append(state.Pending, newStarred.FullName)
+				color.White("[insert] %s repository pushed_at %s",
+					newStarred.FullName, newStarred.PushedAt.Format(time.RFC3339))
+			} else {
+				if oldStarred.PushedAt.Unix() != newStarred.PushedAt.Unix() &&
+					oldStarred.CachedAt.Unix() < time.Now().Add(-24*7*time.Hour).Unix() { // after 7 days.
+					pending[newStarred.FullName] = true
+					state.Pending = append(state.Pending, newStarred.FullName)
+					color.White("[update] %s repository pushed_at %s",
+						newStarred.FullName, newStarred.PushedAt.Format(time.RFC3339))
+				} else if src.languages && oldStarred.Languages == nil {
+					pending[newStarred.FullName] = true
+					state.Pending = append(state.Pending, newStarred.FullName)
+					color.White("[languages] %s repository", newStarred.FullName)
+				}
+			}
+		}
+		state.Listed = state.Fetched
+		if err := s.checkpoint(src, state, nil); err != nil {
+			return fmt.Errorf("[err] sync %w", err)
+		}
+
+		// refresh metadata of cached starred, which also backfills fields added by new versions.
+		var refreshed []*git.Starred
+		for _, fullName := range sortedKeys(staged) {
+			if pending[fullName] {
+				continue
+			}
+			if oldStarred, ok := oldStarredMap[fullName]; ok {
+				if merged, changed := refreshMetadata(oldStarred, staged[fullName]); changed {
+					refreshed = append(refreshed, merged)
+				}
+			}
+		}
+		if len(refreshed) != 0 {
+			color.White("[metadata][%s] %d repositories", host, len(refreshed))
+			s.writeDBAndIndex(src, refreshed)
+		}
+	}
+
+	// fetch readme of pending starred, and write them per chunk.
+	// starred whose readme failed by a transient error are fetched again on next start.
+	pending := state.Pending
+	var deferred []string
+	for len(pending) != 0 {
+		size := readmeChunkSize
+		if len(pending) < size {
+			size = len(pending)
+		}
+		var chunk []*git.Starred
+		for _, fullName := range pending[:size] {
+			if starred, ok := staged[fullName]; ok {
+				chunk = append(chunk, starred)
+			}
+		}
+		src.git.SetReadmeContext(ctx, chunk)
+		if src.languages && ctx.Err() == nil {
+			// languages are optional, so starred are written without them if they failed.
+			if err := src.git.SetLanguagesContext(ctx, chunk); err != nil && ctx.Err() == nil {
+				color.Yellow("[err][%s] languages %s", host, err.Error())
+			}
+		}
+		cancelled := ctx.Err() != nil
+
+		// starred limited by a quota or cancelled are left to pending.
+		var limited []string
+		var written []*git.Starred
+		for _, starred := range chunk {
+			switch {
+			case starred.Error == nil:
+				written = append(written, starred)
+			case cancelled || errors.Is(starred.Error, git.ErrApiQuotaExceed):
+				limited = append(limited, starred.FullName)
+			case git.IsTransient(starred.Error):
+				deferred = append(deferred, starred.FullName)
+				partial.Repos = append(partial.Repos, &git.RepoError{FullName: starred.FullName, Err: starred.Error})
+			default:
+				// a readme which failed by a non-transient error such as 404 is written as empty,
+				// so that the starred is cached and isn't fetched again.
+				color.Yellow("[err][%s] readme %s %s", host, starred.FullName, starred.Error.Error())
+				starred.Error, starred.Readme = nil, ""
+				written = append(written, starred)
+			}
+		}
+		s.writeDBAndIndex(src, written)
+		pending = append(limited, pending[size:]...)
+		state.Pending = append(append([]string{}, deferred...), pending...)
+		if err := s.checkpoint(src, state, nil); err != nil {
+			return fmt.Errorf("[err] sync %w", err)
+		}
+		if cancelled {
+			return fmt.Errorf("[err] sync %d readme pending %w", len(state.Pending), ctx.Err())
+		}
+		if len(limited) != 0 {
+			return fmt.Errorf("[err] sync %d readme pending %w", len(state.Pending), git.ErrApiQuotaExceed)
+		}
+	}
+
+	// unstarred can't be decided until all of pages are fetched.
+	if len(partial.Pages) != 0 || len(partial.Repos) != 0 {
+		return fmt.Errorf("[err] sync %w", partial)
+	}
+
+	// delete starred which are unstarred.
+	// they are kept until next sync, if starred can't be listed again after pages shifted.
+	starredNames, err := listedStarred(ctx, src.git, staged)
+	if err != nil {
+		color.Yellow("[err][%s] unstarred aren't deleted %s", host, err.Error())
+		starredNames = map[string]bool{}
+		for _, oldStarred := range oldStarredList {
+			starredNames[oldStarred.FullName] = true
+		}
+	}
+	var deleteList []*git.Starred
+	for _, oldStarred := range oldStarredList {
+		if !starredNames[oldStarred.FullName] {
+			deleteList = append(deleteList, oldStarred)
+			color.White("[delete] %s repository pushed_at %s",
+				oldStarred.FullName, oldStarred.PushedAt.Format(time.RFC3339))
+		}
+	}
+	s.deleteDBAndIndex(src, deleteList)
+
+	if err := s.resetSync(src, nil); err != nil {
+		return fmt.Errorf("[err] sync %w", err)
+	}
+	return nil
+}
+
+// listStarredPages fetches pages from a cursor, which are fetched concurrently if a client numbers pages.
+func listStarredPages(ctx context.Context, g git.Git, cursor string) ([]*git.Starred, string, error) {
+	if lister, ok := g.(git.PagesLister); ok {
+		return lister.ListStarredPagesContext(ctx, cursor, concurrentPages)
+	}
+	return g.ListStarredPageContext(ctx, cursor)
+}
+
+// listedStarred returns full names of starred, which are staged ones if their count is equal to a count of starred.
+// pages shifted by starring or unstarring during a resumed sync may skip starred,
+// so starred are listed again if the counts differ, and aren't deleted as unstarred.
+func listedStarred(ctx context.Context, g git.Git, staged map[string]*git.Starred) (map[string]bool, error) {
+	names := map[string]bool{}
+	total := len(staged)
+	if counter, ok := g.(git.StarredCounter); ok {
+		count, err := counter.CountStarredContext(ctx)
+		switch {
+		case err == nil:
+			total = count
+		case !errors.Is(err, git.ErrNotFound): // a count which isn't given by a provider is skipped.
+			return nil, fmt.Errorf("[err] listedStarred %w", err)
+		}
+	}
+
+	if total == len(staged) {
+		for fullName := range staged {
+			names[fullName] = true
+		}
+		return names, nil
+	}
+
+	color.White("[relist][%s] %d of %d starred are fetched, so list starred again", g.Host(), len(staged), total)
+	starredList, err := g.ListStarredAllContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[err] listedStarred %w", err)
+	}
+	for _, starred := range starredList {
+		names[starred.FullName] = true
+	}
+	return names, nil
+}
+
+// loadSyncState returns a persisted state of a source, or nil if a sync isn't in progress.
+func (s *searcher) loadSyncState(src *source) (*syncState, error) {
+	var state *syncState
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(syncBucketName))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(src.key))
+		if len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			color.Yellow("[err] collapse sync state, so start over")
+			state = nil
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("[err] loadSyncState %w", err)
+	}
+	return state, nil
+}
+
+// checkpoint persists a state with fetched starred at once.
+func (s *searcher) checkpoint(src *source, state *syncState, fetched []*git.Starred) error {
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("[err] checkpoint %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		staging, err := tx.CreateBucketIfNotExists([]byte(stagingBucketName(src.key)))
+		if err != nil {
+			return err
+		}
+		for _, starred := range fetched {
+			bys, err := json.Marshal(starred)
+			if err != nil {
+				return err
+			}
+			if err := staging.Put([]byte(starred.FullName), bys); err != nil {
+				return err
+			}
+		}
+		bucket, err := tx.CreateBucketIfNotExists([]byte(syncBucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(src.key), stateData)
+	})
+}
+
+// resetSync clears staged starred of a source, and starts a state if it isn't nil.
+func (s *searcher) resetSync(src *source, state *syncState) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(stagingBucketName(src.key))) != nil {
+			if err := tx.DeleteBucket([]byte(stagingBucketName(src.key))); err != nil {
+				return err
+			}
+		}
+		if bucket := tx.Bucket([]byte(syncBucketName)); bucket != nil {
+			return bucket.Delete([]byte(src.key))
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("[err] resetSync %w", err)
+	}
+	if state == nil {
+		return nil
+	}
+	return s.checkpoint(src, state, nil)
+}
+
+// stagedStarred returns starred fetched by a sync.
+func (s *searcher) stagedStarred(src *source) (map[string]*git.Starred, error) {
+	staged := map[string]*git.Starred{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(stagingBucketName(src.key)))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var starred *git.Starred
+			if err := json.Unmarshal(v, &starred); err != nil {
+				color.Yellow("[err] parsing %s", string(k))
+				return nil
+			}
+			staged[starred.FullName] = starred
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("[err] stagedStarred %w", err)
+	}
+	return staged, nil
+}
+
+// refreshMetadata returns cached starred with metadata of fetched one, and whether the metadata is changed.
+// readme, languages and a cached time are kept, so that readme isn't fetched again.
+func refreshMetadata(oldStarred, newStarred *git.Starred) (*git.Starred, bool) {
+	merged := *newStarred
+	merged.Readme = oldStarred.Readme
+	if merged.ReadmePath == "" {
+		merged.ReadmePath = oldStarred.ReadmePath
+	}
+	merged.CachedAt = oldStarred.CachedAt
+	if merged.Languages == nil {
+		merged.Languages = oldStarred.Languages
+	}
+
+	oldData, oldErr := json.Marshal(oldStarred)
+	newData, newErr := json.Marshal(&merged)
+	return &merged, oldErr != nil || newErr != nil || !bytes.Equal(oldData, newData)
+}
+
+// sortedKeys returns keys of starred in order.
+func sortedKeys(m map[string]*git.Starred) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stagingBucketName(key string) string {
+	return key + "_" + syncBucketSuffix
+}
@@ -0,0 +1,425 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGit serves starred of pages, and fails with a quota error after a count of readme.
+// a page of failCursor and readme of failRepo fail by a transient error, and readme of missingRepo isn't found.
+type fakeGit struct {
+	pages        [][]*git.Starred
+	readmeLeft   int
+	calledPage   []string
+	calledReadme []string
+	failCursor   string
+	failRepo     string
+	missingRepo  string
+	languages    map[string]map[string]int
+}
+
+func (f *fakeGit) Provider() string         { return git.ProviderGithub }
+func (f *fakeGit) Host() string             { return "github.com" }
+func (f *fakeGit) RateLimit() git.RateLimit { return git.RateLimit{} }
+func (f *fakeGit) SetCache(cache git.Cache) {}
+func (f *fakeGit) UserContext(ctx context.Context) (*git.User, error) {
+	return &git.User{Owner: "allan"}, nil
+}
+func (f *fakeGit) ListStarredAllContext(ctx context.Context) ([]*git.Starred, error) {
+	return nil, nil
+}
+func (f *fakeGit) ListReadmeContext(ctx context.Context, owners []string, repos []string) ([]*git.Readme, error) {
+	return nil, nil
+}
+
+func (f *fakeGit) ListStarredPageContext(ctx context.Context, cursor string) ([]*git.Starred, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	f.calledPage = append(f.calledPage, cursor)
+	if cursor != "" && cursor == f.failCursor {
+		return nil, "", errors.New("502 bad gateway")
+	}
+	page, _ := strconv.Atoi(cursor)
+	if page == 0 {
+		page = 1
+	}
+	next := strconv.Itoa(page + 1)
+	if page >= len(f.pages) {
+		next = ""
+	}
+	var list []*git.Starred
+	for _, starred := range f.pages[page-1] {
+		copied := *starred
+		list = append(list, &copied)
+	}
+	return list, next, nil
+}
+
+func (f *fakeGit) SetReadmeContext(ctx context.Context, starred []*git.Starred) {
+	for _, s := range starred {
+		if err := ctx.Err(); err != nil {
+			s.Error = err
+			continue
+		}
+		f.calledReadme = append(f.calledReadme, s.FullName)
+		if s.FullName == f.failRepo {
+			s.Error = errors.New("502 bad gateway")
+			continue
+		}
+		if s.FullName == f.missingRepo {
+			s.Error = fmt.Errorf("[err] SetReadme %w", git.ErrNotFound)
+			continue
+		}
+		if f.readmeLeft <= 0 {
+			s.Error = fmt.Errorf("[err] SetReadme %w", git.ErrApiQuotaExceed)
+			continue
+		}
+		f.readmeLeft--
+		s.Readme = "readme of " + s.FullName
+	}
+}
+
+func (f *fakeGit) SetLanguagesContext(ctx context.Context, starred []*git.Starred) error {
+	for _, s := range starred {
+		s.Languages = map[string]int{}
+		for name, size := range f.languages[s.FullName] {
+			s.Languages[name] = size
+		}
+	}
+	return ctx.Err()
+}
+
+func TestSearcher_Sync(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := openIndex(filepath.Join(dir, indexDirName))
+	assert.NoError(err)
+	defer index.Close()
+
+	var pages [][]*git.Starred
+	for p := 0; p < 3; p++ {
+		var page []*git.Starred
+		for i := 0; i < readmeChunkSize; i++ {
+			name := fmt.Sprintf("allan/repo-%d-%d", p, i)
+			page = append(page, &git.Starred{Owner: "allan", Repo: name[6:], FullName: name})
+		}
+		pages = append(pages, page)
+	}
+	g := &fakeGit{pages: pages, readmeLeft: readmeChunkSize + 10}
+	src := &source{key: "allan@github.com", git: g}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+
+	tests := map[string]struct {
+		cancel     bool
+		readmeLeft int
+		isErr      bool
+		pages      []string
+		written    int
+		pending    int
+	}{
+		"cancelled":      {cancel: true, readmeLeft: readmeChunkSize + 10, isErr: true, pages: []string{}},
+		"quota exceeded": {readmeLeft: readmeChunkSize + 10, isErr: true, pages: []string{"", "2", "3"}, written: readmeChunkSize + 10, pending: 2*readmeChunkSize - 10},
+		"resume":         {readmeLeft: 1000, pages: []string{}, written: 3 * readmeChunkSize},
+	}
+
+	for _, name := range []string{"cancelled", "quota exceeded", "resume"} {
+		t := tests[name]
+		g.readmeLeft = t.readmeLeft
+		g.calledPage = []string{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if t.cancel {
+			cancel()
+		}
+		_, oldStarredList, err := s.readStarred(src)
+		assert.NoError(err, name)
+		err = s.sync(ctx, src, oldStarredList)
+		cancel()
+		assert.Equal(t.isErr, err != nil, name)
+		assert.Equal(t.cancel, errors.Is(err, context.Canceled), name)
+		assert.Equal(t.pages, g.calledPage, name)
+
+		state, err := s.loadSyncState(src)
+		assert.NoError(err, name)
+		switch {
+		case t.cancel:
+			// a sync is saved before the first page.
+			assert.NotNil(state, name)
+			assert.Empty(state.Cursor, name)
+		case t.pending == 0:
+			assert.Nil(state, name)
+		default:
+			assert.Len(state.Pending, t.pending, name)
+		}
+
+		total, err := s.TotalDoc()
+		assert.NoError(err, name)
+		assert.Equal(t.written, total, name)
+	}
+
+	// unstarred repositories are deleted by a new sync.
+	g.pages = pages[:1]
+	_, oldStarredList, err := s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.sync(context.Background(), src, oldStarredList))
+	total, err := s.TotalDoc()
+	assert.NoError(err)
+	assert.Equal(readmeChunkSize, total)
+
+	// metadata of cached starred is backfilled without fetching readme.
+	pages[0][0].Language, pages[0][0].Archived = "Go", true
+	g.readmeLeft = 0
+	_, oldStarredList, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.sync(context.Background(), src, oldStarredList))
+	_, newStarredList, err := s.readStarred(src)
+	assert.NoError(err)
+	for _, starred := range newStarredList {
+		if starred.FullName == pages[0][0].FullName {
+			assert.Equal("Go", starred.Language)
+			assert.True(starred.Archived)
+			assert.Equal("readme of "+starred.FullName, starred.Readme)
+		}
+	}
+	q, err := ParseQuery("language:go archived:true")
+	assert.NoError(err)
+	result, err := index.Search(bleve.NewSearchRequest(q))
+	assert.NoError(err)
+	assert.Equal(uint64(1), result.Total)
+
+	// languages of cached starred are fetched once they are enabled.
+	src.languages = true
+	g.readmeLeft = 1000
+	g.languages = map[string]map[string]int{
+		pages[0][1].FullName: {"Rust": 9000, "Shell": 1000},
+		pages[0][2].FullName: {"Shell": 9000, "Rust": 1000},
+	}
+	_, oldStarredList, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.sync(context.Background(), src, oldStarredList))
+	_, newStarredList, err = s.readStarred(src)
+	assert.NoError(err)
+	for _, starred := range newStarredList {
+		assert.NotNil(starred.Languages, starred.FullName)
+	}
+	found, err := s.Search("lang:rust", 0)
+	assert.NoError(err)
+	assert.Len(found, 2)
+
+	// languages aren't fetched again.
+	g.languages = nil
+	_, oldStarredList, err = s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.sync(context.Background(), src, oldStarredList))
+	found, err = s.Search("lang:rust", 0)
+	assert.NoError(err)
+	assert.Len(found, 2)
+}
+
+func TestSearcher_SyncPartial(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := openIndex(filepath.Join(dir, indexDirName))
+	assert.NoError(err)
+	defer index.Close()
+
+	var pages [][]*git.Starred
+	for p := 0; p < 2; p++ {
+		var page []*git.Starred
+		for i := 0; i < 3; i++ {
+			name := fmt.Sprintf("allan/repo-%d-%d", p, i)
+			page = append(page, &git.Starred{Owner: "allan", Repo: name[6:], FullName: name})
+		}
+		pages = append(pages, page)
+	}
+	g := &fakeGit{pages: pages, readmeLeft: 1000, failCursor: "2", failRepo: "allan/repo-0-0"}
+	src := &source{key: "allan@github.com", git: g}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+
+	tests := map[string]struct {
+		failCursor string
+		failRepo   string
+		pages      int
+		repos      int
+		written    int
+		pending    []string
+	}{
+		"partial": {failCursor: "2", failRepo: "allan/repo-0-0", pages: 1, repos: 1, written: 2, pending: []string{"allan/repo-0-0"}},
+		"resume":  {written: 6},
+	}
+
+	for _, name := range []string{"partial", "resume"} {
+		t := tests[name]
+		g.failCursor, g.failRepo = t.failCursor, t.failRepo
+
+		_, oldStarredList, err := s.readStarred(src)
+		assert.NoError(err, name)
+		err = s.sync(context.Background(), src, oldStarredList)
+
+		var partial *git.PartialError
+		assert.Equal(t.pages+t.repos != 0, errors.As(err, &partial), name)
+		if partial != nil {
+			assert.Len(partial.Pages, t.pages, name)
+			assert.Len(partial.Repos, t.repos, name)
+		}
+
+		// what succeeded is written, and the rest is left to a sync state.
+		state, err := s.loadSyncState(src)
+		assert.NoError(err, name)
+		if len(t.pending) == 0 {
+			assert.Nil(state, name)
+		} else {
+			assert.Equal(t.pending, state.Pending, name)
+			assert.Equal(t.failCursor, state.Cursor, name)
+		}
+		total, err := s.TotalDoc()
+		assert.NoError(err, name)
+		assert.Equal(t.written, total, name)
+	}
+}
+
+func TestSearcher_SyncMissingReadme(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	indexPath := filepath.Join(dir, indexDirName)
+	index, err := openIndex(indexPath)
+	assert.NoError(err)
+
+	page := []*git.Starred{
+		{Owner: "allan", Repo: "hello", FullName: "allan/hello", Description: "hello cli"},
+		{Owner: "allan", Repo: "empty", FullName: "allan/empty", Description: "empty cli"},
+	}
+	g := &fakeGit{pages: [][]*git.Starred{page}, readmeLeft: 1000, missingRepo: "allan/empty"}
+	s := &searcher{db: db, index: index, indexPath: indexPath, sources: []*source{{git: g}}}
+	// the index is rebuilt at the first time, because it's made without an owner.
+	defer func() { s.index.Close() }()
+
+	tests := map[string]struct {
+		readme []string
+	}{
+		"first":  {readme: []string{"allan/empty", "allan/hello"}},
+		"second": {readme: nil},
+	}
+
+	for _, name := range []string{"first", "second"} {
+		t := tests[name]
+		g.calledReadme = nil
+		assert.NoError(s.CreateIndexContext(context.Background()), name)
+		assert.ElementsMatch(t.readme, g.calledReadme, name)
+
+		// a repository without readme is cached and indexed.
+		total, err := s.TotalDoc()
+		assert.NoError(err, name)
+		assert.Equal(2, total, name)
+		found, err := s.Search("empty", 0)
+		assert.NoError(err, name)
+		assert.Len(found, 1, name)
+		assert.Equal("allan/empty", found[0].FullName, name)
+		assert.Empty(found[0].Readme, name)
+	}
+}
+
+// countingGit counts and lists all of starred, which may differ from pages shifted during a sync.
+type countingGit struct {
+	*fakeGit
+	all      []*git.Starred
+	countErr error
+}
+
+func (c *countingGit) CountStarredContext(ctx context.Context) (int, error) {
+	return len(c.all), c.countErr
+}
+
+func (c *countingGit) ListStarredAllContext(ctx context.Context) ([]*git.Starred, error) {
+	return c.all, nil
+}
+
+func TestSearcher_SyncShifted(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := openIndex(filepath.Join(dir, indexDirName))
+	assert.NoError(err)
+	defer index.Close()
+
+	var all []*git.Starred
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("allan/repo-%d", i)
+		all = append(all, &git.Starred{Owner: "allan", Repo: name[6:], FullName: name})
+	}
+	g := &countingGit{fakeGit: &fakeGit{pages: [][]*git.Starred{all[:3], all[3:]}, readmeLeft: 1000}, all: all}
+	src := &source{key: "allan@github.com", git: g}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, oldStarredList, err := s.readStarred(src)
+	assert.NoError(err)
+	assert.NoError(s.sync(context.Background(), src, oldStarredList))
+
+	tests := map[string]struct {
+		all      []*git.Starred
+		countErr error
+		written  int
+	}{
+		// a skipped repository is found by listing again.
+		"shifted": {all: all, written: 6},
+		// a count which isn't given by a provider is skipped, so staged ones are trusted.
+		"not counted":  {all: all, countErr: git.ErrNotFound, written: 5},
+		"count failed": {all: all, countErr: errors.New("502 bad gateway"), written: 6},
+		"unstarred":    {all: append(append([]*git.Starred{}, all[:3]...), all[4:]...), written: 5},
+	}
+
+	for _, name := range []string{"shifted", "count failed", "not counted", "unstarred"} {
+		t := tests[name]
+		g.all, g.countErr = t.all, t.countErr
+		g.pages = [][]*git.Starred{all[:3], all[4:]}
+
+		_, oldStarredList, err = s.readStarred(src)
+		assert.NoError(err, name)
+		assert.NoError(s.sync(context.Background(), src, oldStarredList), name)
+		total, err := s.TotalDoc()
+		assert.NoError(err, name)
+		assert.Equal(t.written, total, name)
+	}
+}
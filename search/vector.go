@@ -0,0 +1,577 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/boltdb/bolt"
+	"github.com/fatih/color"
+	"github.com/gjbae1212/findgs/git"
+)
+
+const (
+	ModeKeyword = "keyword"
+	ModeVector  = "vector"
+	ModeHybrid  = "hybrid"
+)
+
+const (
+	vectorBucketName        = "vectors"          // hashed vectors per document id, with a hash of an embedded text.
+	semanticBucketName      = "semantic_vectors" // vectors projected to a latent semantic space per document id.
+	semanticModelBucketName = "semantic_model"
+
+	// vectorVersion should be changed when hashed vectors or a semantic model are computed differently.
+	vectorVersion = "1"
+	// vectorDim is a dimension of hashed vectors of n-grams.
+	vectorDim = 1024
+	// semanticDim is a dimension of a latent semantic space, which is at most a count of documents.
+	semanticDim = 48
+	// semanticIterations is a count of subspace iterations which approximate singular vectors.
+	semanticIterations = 3
+	// maxNeighbors is a count of the nearest repositories which are found by a vector.
+	maxNeighbors = 50
+	// hybridWeight is a weight of a keyword score in hybrid ranking, and the rest is of a vector similarity.
+	hybridWeight = 0.5
+)
+
+var (
+	// Modes are ranking modes of a search, which are keyword by default.
+	Modes = []string{ModeKeyword, ModeVector, ModeHybrid}
+
+	// featureWeights are weights of words, word bigrams and character trigrams in a hashed vector.
+	featureWeights = map[byte]float64{'w': 1, 'b': 0.5, 't': 0.25}
+	// embeddedWeights are weights of fields of starred, whose texts are normalized before they are weighted.
+	embeddedWeights = map[string]float64{"name": 2, "topic": 2, "desc": 2, "readme": 1}
+
+	modelVersionKey = []byte("version")
+	modelBasisKey   = []byte("basis")
+)
+
+var (
+	ErrNotFoundVectors = errors.New("[err] Not found vectors, index with --semantic option")
+)
+
+// semanticIndex is a latent semantic space with projected vectors of documents.
+type semanticIndex struct {
+	basis   [][]float64
+	ids     []string
+	vectors [][]float32
+}
+
+type neighbor struct {
+	id         string
+	similarity float64
+}
+
+// searchSemantic searches the nearest repositories of a text in a latent semantic space,
+// which are ranked by a vector similarity or by a hybrid of it and a keyword score.
+func (s *searcher) searchSemantic(ctx context.Context, text string, mode string, opts *SearchOptions) (*bleve.SearchResult, error) {
+	if opts.Explain {
+		return nil, fmt.Errorf("%w explain isn't supported in %s mode", ErrInvalidParam, mode)
+	}
+	semanticIdx, err := s.loadSemantic()
+	if err != nil {
+		return nil, err
+	}
+	analyzer := s.index.Mapping().AnalyzerNamed(vectorAnalyzer)
+	if semanticIdx == nil || analyzer == nil {
+		return nil, ErrNotFoundVectors
+	}
+
+	// filters are applied before ranking, so that the nearest repositories aren't taken by filtered ones.
+	allowed, err := s.filteredIDs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	similarities := map[string]float64{}
+	var ids []string
+	for _, n := range semanticIdx.nearest(semanticIdx.project(hashedVector(analyzer, map[string]string{"desc": text})), maxNeighbors, allowed) {
+		similarities[n.id] = n.similarity
+		ids = append(ids, n.id)
+	}
+
+	// the nearest repositories are searched with the keyword query, so that facets and sorts are applied to them.
+	// keyword scores are taken from the keyword query searched alone, because a disjunction scales scores of its hits.
+	var q query.Query = bleve.NewDocIDQuery(ids)
+	weight := 0.0
+	keywordScores := map[string]float64{}
+	if mode == ModeHybrid {
+		kq, err := ParseQuery(text)
+		if err != nil {
+			return nil, err
+		}
+		keywordResult, err := s.searchQuery(ctx, kq, &SearchOptions{Filters: opts.Filters})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range keywordResult.Hits {
+			keywordScores[d.ID] = d.Score
+		}
+		weight = hybridWeight
+		q = bleve.NewDisjunctionQuery(kq, q)
+	}
+	// all of hits are searched, because they are paged after scores are replaced.
+	searchResult, err := s.searchQuery(ctx, q, &SearchOptions{Sort: opts.Sort, Filters: opts.Filters, Facets: opts.Facets})
+	if err != nil {
+		return nil, err
+	}
+
+	// scores are replaced by a vector similarity, which is mixed with a normalized keyword score in hybrid mode.
+	searchResult.MaxScore = 0
+	for _, d := range searchResult.Hits {
+		d.Score = weight*normalizeScore(keywordScores[d.ID]) + (1-weight)*similarities[d.ID]
+		d.Expl = nil
+		if d.Score > searchResult.MaxScore {
+			searchResult.MaxScore = d.Score
+		}
+	}
+	if opts.Sort == "" || strings.ToLower(opts.Sort) == SortScore {
+		sort.SliceStable(searchResult.Hits, func(i, j int) bool {
+			if searchResult.Hits[i].Score != searchResult.Hits[j].Score {
+				return searchResult.Hits[i].Score > searchResult.Hits[j].Score
+			}
+			return searchResult.Hits[i].ID < searchResult.Hits[j].ID
+		})
+	}
+	return searchResult, nil
+}
+
+// filteredIDs returns ids of repositories which pass filters of options, which is nil without filters.
+func (s *searcher) filteredIDs(ctx context.Context, opts *SearchOptions) (map[string]bool, error) {
+	if len(opts.Filters) == 0 {
+		return nil, nil
+	}
+	searchResult, err := s.searchQuery(ctx, bleve.NewMatchAllQuery(), &SearchOptions{Filters: opts.Filters})
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]bool{}
+	for _, d := range searchResult.Hits {
+		ids[d.ID] = true
+	}
+	return ids, nil
+}
+
+// loadSemantic returns a latent semantic space in the db, which is nil if vectors haven't been computed.
+func (s *searcher) loadSemantic() (*semanticIndex, error) {
+	if s.semantic != nil {
+		return s.semantic, nil
+	}
+	semanticIdx := &semanticIndex{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		model := tx.Bucket([]byte(semanticModelBucketName))
+		vectors := tx.Bucket([]byte(semanticBucketName))
+		if model == nil || vectors == nil || string(model.Get(modelVersionKey)) != vectorVersion {
+			semanticIdx = nil
+			return nil
+		}
+		basis := decodeVector(model.Get(modelBasisKey))
+		for i := 0; i+vectorDim <= len(basis); i += vectorDim {
+			b := make([]float64, vectorDim)
+			for j := range b {
+				b[j] = float64(basis[i+j])
+			}
+			semanticIdx.basis = append(semanticIdx.basis, b)
+		}
+		return vectors.ForEach(func(k, v []byte) error {
+			semanticIdx.ids = append(semanticIdx.ids, string(k))
+			semanticIdx.vectors = append(semanticIdx.vectors, decodeVector(v))
+			return nil
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("[err] loadSemantic %w", err)
+	}
+	s.semantic = semanticIdx
+	return semanticIdx, nil
+}
+
+// project projects a hashed vector to a latent semantic space, and it's normalized.
+func (idx *semanticIndex) project(vector []float32) []float32 {
+	projected := make([]float64, len(idx.basis))
+	for i, b := range idx.basis {
+		projected[i] = dot(vector, b)
+	}
+	normalize(projected)
+	return toFloat32(projected)
+}
+
+// nearest returns at most n documents in order of a cosine similarity, whose similarities are positive.
+// only allowed documents are ranked unless allowed is nil.
+func (idx *semanticIndex) nearest(vector []float32, n int, allowed map[string]bool) []*neighbor {
+	var neighbors []*neighbor
+	for i, v := range idx.vectors {
+		if allowed != nil && !allowed[idx.ids[i]] {
+			continue
+		}
+		var similarity float64
+		for j := 0; j < len(v) && j < len(vector); j++ {
+			similarity += float64(v[j]) * float64(vector[j])
+		}
+		if similarity > 0 {
+			neighbors = append(neighbors, &neighbor{id: idx.ids[i], similarity: similarity})
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].similarity != neighbors[j].similarity {
+			return neighbors[i].similarity > neighbors[j].similarity
+		}
+		return neighbors[i].id < neighbors[j].id
+	})
+	if len(neighbors) > n {
+		neighbors = neighbors[:n]
+	}
+	return neighbors
+}
+
+// syncVectors computes hashed vectors of cached starred which are new or changed, and removes vectors of unstarred.
+// a latent semantic space is computed again if any vector is changed.
+func (s *searcher) syncVectors(ctx context.Context) error {
+	analyzer := s.index.Mapping().AnalyzerNamed(vectorAnalyzer)
+	if analyzer == nil {
+		return fmt.Errorf("[err] syncVectors %w", ErrNotFoundVectors)
+	}
+
+	docs := map[string]*git.Starred{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		for _, src := range s.sources {
+			bucket := tx.Bucket([]byte(starredBucketName(src.key)))
+			if bucket == nil {
+				continue
+			}
+			if err := bucket.ForEach(func(k, v []byte) error {
+				var starred *git.Starred
+				if err := json.Unmarshal(v, &starred); err == nil {
+					docs[docID(src, starred)] = starred
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("[err] syncVectors %w", err)
+	}
+
+	// a model is removed while vectors are changed, so that it's computed again even if computing is stopped.
+	changed := 0
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(vectorBucketName))
+		if err != nil {
+			return err
+		}
+		model, err := tx.CreateBucketIfNotExists([]byte(semanticModelBucketName))
+		if err != nil {
+			return err
+		}
+		if string(model.Get(modelVersionKey)) != vectorVersion {
+			changed++
+		}
+
+		var stale [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			if _, ok := docs[string(k)]; !ok {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			changed++
+		}
+
+		for id, starred := range docs {
+			if ctx.Err() != nil {
+				break
+			}
+			texts := embeddedTexts(starred)
+			hash := textHash(texts)
+			if stored := bucket.Get([]byte(id)); len(stored) >= len(hash) && bytes.Equal(stored[:len(hash)], hash) {
+				continue
+			}
+			if err := bucket.Put([]byte(id), append(hash, encodeVector(hashedVector(analyzer, texts))...)); err != nil {
+				return err
+			}
+			changed++
+		}
+		if changed == 0 {
+			return nil
+		}
+		return model.Delete(modelVersionKey)
+	}); err != nil {
+		return fmt.Errorf("[err] syncVectors %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("[err] syncVectors %w", err)
+	}
+	if changed == 0 {
+		return nil
+	}
+
+	color.Cyan("[semantic] compute vectors of %d repositories", len(docs))
+	if err := s.computeSemantic(); err != nil {
+		return fmt.Errorf("[err] syncVectors %w", err)
+	}
+	return nil
+}
+
+// computeSemantic computes a latent semantic space of hashed vectors, and stores projected vectors of documents.
+// the whole space is computed again even if a vector is changed, which costs O(documents*vectorDim*semanticDim*semanticIterations).
+func (s *searcher) computeSemantic() error {
+	var ids []string
+	var vectors [][]float32
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(vectorBucketName))
+		if bucket == nil {
+			return nil
+		}
+		hashSize := len(textHash(nil))
+		return bucket.ForEach(func(k, v []byte) error {
+			if len(v) < hashSize {
+				return nil
+			}
+			ids = append(ids, string(k))
+			vectors = append(vectors, decodeVector(v[hashSize:]))
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	semanticIdx := &semanticIndex{basis: semanticBasis(vectors, semanticDim, semanticIterations), ids: ids}
+	var basis []float32
+	for _, b := range semanticIdx.basis {
+		basis = append(basis, toFloat32(b)...)
+	}
+	for _, v := range vectors {
+		semanticIdx.vectors = append(semanticIdx.vectors, semanticIdx.project(v))
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(semanticBucketName)) != nil {
+			if err := tx.DeleteBucket([]byte(semanticBucketName)); err != nil {
+				return err
+			}
+		}
+		bucket, err := tx.CreateBucket([]byte(semanticBucketName))
+		if err != nil {
+			return err
+		}
+		for i, id := range semanticIdx.ids {
+			if err := bucket.Put([]byte(id), encodeVector(semanticIdx.vectors[i])); err != nil {
+				return err
+			}
+		}
+		model, err := tx.CreateBucketIfNotExists([]byte(semanticModelBucketName))
+		if err != nil {
+			return err
+		}
+		if err := model.Put(modelBasisKey, encodeVector(basis)); err != nil {
+			return err
+		}
+		return model.Put(modelVersionKey, []byte(vectorVersion))
+	}); err != nil {
+		return err
+	}
+	s.semantic = semanticIdx
+	return nil
+}
+
+// embeddedTexts returns texts of fields of starred which are embedded to a vector.
+// code blocks of README aren't embedded, because they are mostly identifiers rather than meaning.
+func embeddedTexts(starred *git.Starred) map[string]string {
+	readme := parseMarkdown(starred.Readme)
+	return map[string]string{
+		"name":   strings.NewReplacer("/", " ", "-", " ", "_", " ", ".", " ").Replace(starred.FullName),
+		"topic":  strings.Join(starred.Topics, " "),
+		"desc":   starred.Description,
+		"readme": strings.TrimSpace(readme.Headings + "\n" + readme.Prose),
+	}
+}
+
+// textHash returns a hash of embedded texts, by which a changed repository is found.
+func textHash(texts map[string]string) []byte {
+	h := fnv.New64a()
+	h.Write([]byte(vectorVersion))
+	for _, field := range []string{"name", "topic", "desc", "readme"} {
+		h.Write([]byte{0})
+		h.Write([]byte(texts[field]))
+	}
+	return h.Sum(nil)
+}
+
+// hashedVector returns a normalized vector of texts per field, whose words, word bigrams and character trigrams
+// are hashed to signed dimensions. each field is normalized before it's weighted, so a long README doesn't outweigh a name.
+func hashedVector(analyzer *analysis.Analyzer, texts map[string]string) []float32 {
+	vector := make([]float64, vectorDim)
+	for field, text := range texts {
+		if text == "" {
+			continue
+		}
+		part := make([]float64, vectorDim)
+		for feature, count := range ngrams(analyzer, text) {
+			h := fnv.New32a()
+			h.Write([]byte(feature))
+			sum := h.Sum32()
+			sign := 1.0
+			if sum&(1<<31) != 0 {
+				sign = -1.0
+			}
+			// a frequency is sublinear, so that a repeated word doesn't dominate a vector.
+			part[sum%vectorDim] += sign * featureWeights[feature[0]] * (1 + math.Log(float64(count)))
+		}
+		normalize(part)
+		weight, ok := embeddedWeights[field]
+		if !ok {
+			weight = 1
+		}
+		for i := range vector {
+			vector[i] += weight * part[i]
+		}
+	}
+	normalize(vector)
+	return toFloat32(vector)
+}
+
+// ngrams returns counts of words, word bigrams and character trigrams of a text, which are prefixed by their kinds.
+func ngrams(analyzer *analysis.Analyzer, text string) map[string]int {
+	counts := map[string]int{}
+	var prev string
+	for _, token := range analyzer.Analyze([]byte(text)) {
+		word := string(token.Term)
+		counts["w:"+word]++
+		if prev != "" {
+			counts["b:"+prev+" "+word]++
+		}
+		prev = word
+
+		runes := []rune("<" + word + ">")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts["t:"+string(runes[i:i+3])]++
+		}
+	}
+	return counts
+}
+
+// semanticBasis returns an orthonormal basis of the top singular vectors of vectors, which spans a latent semantic space.
+// they are approximated by subspace iterations from a random basis with a fixed seed, so the same vectors make the same basis.
+func semanticBasis(vectors [][]float32, dim, iterations int) [][]float64 {
+	if dim > len(vectors) {
+		dim = len(vectors)
+	}
+	r := rand.New(rand.NewSource(1))
+	basis := make([][]float64, dim)
+	for i := range basis {
+		basis[i] = make([]float64, vectorDim)
+		for j := range basis[i] {
+			basis[i][j] = r.NormFloat64()
+		}
+	}
+	orthonormalize(basis)
+
+	for n := 0; n < iterations; n++ {
+		next := make([][]float64, dim)
+		for i := range next {
+			next[i] = make([]float64, vectorDim)
+		}
+		// next = AᵀA basis, where rows of A are vectors.
+		for _, v := range vectors {
+			for i, b := range basis {
+				p := dot(v, b)
+				if p == 0 {
+					continue
+				}
+				for j := 0; j < len(v) && j < vectorDim; j++ {
+					next[i][j] += p * float64(v[j])
+				}
+			}
+		}
+		basis = next
+		orthonormalize(basis)
+	}
+	return basis
+}
+
+// orthonormalize makes vectors orthonormal by Gram-Schmidt, and a dependent vector becomes zero.
+func orthonormalize(vectors [][]float64) {
+	for i := range vectors {
+		for j := 0; j < i; j++ {
+			var p float64
+			for k := range vectors[i] {
+				p += vectors[i][k] * vectors[j][k]
+			}
+			for k := range vectors[i] {
+				vectors[i][k] -= p * vectors[j][k]
+			}
+		}
+		if !normalize(vectors[i]) {
+			for k := range vectors[i] {
+				vectors[i][k] = 0
+			}
+		}
+	}
+}
+
+// normalize makes a vector of unit length, and returns false if it's too short to be normalized.
+func normalize(vector []float64) bool {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm < 1e-9 {
+		return false
+	}
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return true
+}
+
+func dot(a []float32, b []float64) float64 {
+	var sum float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		sum += float64(a[i]) * b[i]
+	}
+	return sum
+}
+
+func toFloat32(vector []float64) []float32 {
+	converted := make([]float32, len(vector))
+	for i, v := range vector {
+		converted[i] = float32(v)
+	}
+	return converted
+}
+
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
@@ -0,0 +1,188 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gjbae1212/findgs/git"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashedVector(t *testing.T) {
+	assert := assert.New(t)
+
+	analyzer := newIndexMapping().AnalyzerNamed(vectorAnalyzer)
+	assert.NotNil(analyzer)
+
+	cosine := func(a, b string) float64 {
+		return dot(hashedVector(analyzer, map[string]string{"desc": a}), toFloat64(hashedVector(analyzer, map[string]string{"desc": b})))
+	}
+	tests := map[string]struct {
+		input  string
+		like   string
+		unlike string
+	}{
+		"words":    {input: "command line tool", like: "a tool for the command line", unlike: "chocolate cake recipes"},
+		"trigrams": {input: "configuration", like: "configurations", unlike: "database"},
+	}
+
+	for name, t := range tests {
+		assert.Greater(cosine(t.input, t.like), cosine(t.input, t.unlike), name)
+	}
+	assert.InDelta(1.0, cosine("command line tool", "command line tool"), 1e-5)
+	assert.Equal(hashedVector(analyzer, map[string]string{"desc": "cli"}), hashedVector(analyzer, map[string]string{"desc": "cli"}))
+}
+
+func TestSemanticBasis(t *testing.T) {
+	assert := assert.New(t)
+
+	analyzer := newIndexMapping().AnalyzerNamed(vectorAnalyzer)
+	var vectors [][]float32
+	for _, text := range []string{"command line tool", "cli framework", "web framework", "json diff", "cake recipes"} {
+		vectors = append(vectors, hashedVector(analyzer, map[string]string{"desc": text}))
+	}
+
+	// a dimension is at most a count of vectors, and a basis is orthonormal.
+	basis := semanticBasis(vectors, semanticDim, semanticIterations)
+	assert.Len(basis, len(vectors))
+	for i := range basis {
+		for j := range basis {
+			expected := 0.0
+			if i == j {
+				expected = 1.0
+			}
+			assert.InDelta(expected, dot(toFloat32(basis[i]), basis[j]), 1e-4)
+		}
+	}
+	assert.Equal(basis, semanticBasis(vectors, semanticDim, semanticIterations))
+}
+
+func TestSearcher_SearchSemantic(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "findgs")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), os.ModePerm, &bolt.Options{Timeout: time.Second})
+	assert.NoError(err)
+	defer db.Close()
+	index, err := openIndex(filepath.Join(dir, indexDirName))
+	assert.NoError(err)
+	defer index.Close()
+
+	src := &source{key: "allan@github.com", git: &fakeGit{}}
+	s := &searcher{db: db, index: index, sources: []*source{src}}
+	_, _, err = s.readStarred(src)
+	assert.NoError(err)
+
+	docs := []*git.Starred{
+		{Owner: "spf13", Repo: "cobra", FullName: "spf13/cobra", Description: "A Commander for modern Go CLI interactions",
+			Topics: []string{"cli", "go"}, Readme: "Cobra is a library for creating powerful modern CLI applications with subcommands and flags."},
+		{Owner: "urfave", Repo: "cli", FullName: "urfave/cli", Description: "A simple, fast, and fun package for building command line apps in Go",
+			Topics: []string{"cli", "go"}, Readme: "cli is a package for building command line applications with subcommands and flags."},
+		{Owner: "spf13", Repo: "viper", FullName: "spf13/viper", Description: "Go configuration with fangs",
+			Topics: []string{"config", "go"}, Readme: "Viper is a complete configuration solution for Go applications."},
+		{Owner: "allan", Repo: "cake", FullName: "allan/cake", Description: "cake recipes",
+			Readme: "Recipes of chocolate cake."},
+	}
+	assert.NoError(s.writeDBAndIndex(src, docs))
+
+	// vectors aren't computed without a semantic mode.
+	_, err = s.SearchWith(context.Background(), "command line", &SearchOptions{Mode: ModeVector})
+	assert.True(errors.Is(err, ErrNotFoundVectors))
+
+	assert.NoError(s.syncVectors(context.Background()))
+	assert.NotNil(s.semantic)
+	assert.Len(s.semantic.ids, len(docs))
+
+	// vectors are kept if starred aren't changed.
+	s.semantic = nil
+	assert.NoError(s.syncVectors(context.Background()))
+	assert.Nil(s.semantic)
+
+	tests := map[string]struct {
+		input *SearchOptions
+		text  string
+		first string
+		isErr bool
+	}{
+		"vector":   {input: &SearchOptions{Mode: ModeVector}, text: "building command line applications", first: "urfave/cli"},
+		"trigrams": {input: &SearchOptions{Mode: "VECTOR"}, text: "configurations", first: "spf13/viper"},
+		"hybrid":   {input: &SearchOptions{Mode: ModeHybrid}, text: "cobra", first: "spf13/cobra"},
+		"filter":   {input: &SearchOptions{Mode: ModeVector, Filters: []string{"stars>0"}}, text: "command line"},
+		"empty":    {input: &SearchOptions{Mode: ModeVector}, text: ""},
+		"explain":  {input: &SearchOptions{Mode: ModeHybrid, Explain: true}, text: "cobra", isErr: true},
+		"unknown":  {input: &SearchOptions{Mode: "neural"}, text: "cobra", isErr: true},
+	}
+
+	for name, t := range tests {
+		page, err := s.SearchWith(context.Background(), t.text, t.input)
+		assert.Equal(t.isErr, err != nil, name)
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, found := range page.Results {
+			names = append(names, found.FullName)
+			assert.False(math.IsNaN(found.Score), name)
+		}
+		if t.first == "" {
+			assert.Empty(names, name)
+			continue
+		}
+		assert.Equal(t.first, names[0], name)
+		assert.True(page.Results[0].Score > 0 && page.Results[0].Score <= 1, name)
+	}
+
+	// keyword scores of hybrid mode are scores of the keyword query.
+	kq, err := ParseQuery("cobra cli")
+	assert.NoError(err)
+	keywordResult, err := s.searchQuery(context.Background(), kq, &SearchOptions{})
+	assert.NoError(err)
+	vectorResult, err := s.searchSemantic(context.Background(), "cobra cli", ModeVector, &SearchOptions{})
+	assert.NoError(err)
+	hybridResult, err := s.searchSemantic(context.Background(), "cobra cli", ModeHybrid, &SearchOptions{})
+	assert.NoError(err)
+	keywords, similarities := map[string]float64{}, map[string]float64{}
+	for _, d := range keywordResult.Hits {
+		keywords[d.ID] = d.Score
+	}
+	for _, d := range vectorResult.Hits {
+		similarities[d.ID] = d.Score
+	}
+	assert.NotEmpty(hybridResult.Hits)
+	for _, d := range hybridResult.Hits {
+		assert.InDelta(hybridWeight*normalizeScore(keywords[d.ID])+(1-hybridWeight)*similarities[d.ID], d.Score, 1e-9, d.ID)
+	}
+
+	// filtered repositories aren't ranked.
+	vector := s.semantic.project(hashedVector(newIndexMapping().AnalyzerNamed(vectorAnalyzer), map[string]string{"desc": "building command line applications"}))
+	neighbors := s.semantic.nearest(vector, 1, nil)
+	assert.Len(neighbors, 1)
+	assert.NotEqual("github.com/spf13/viper", neighbors[0].id)
+	neighbors = s.semantic.nearest(vector, 1, map[string]bool{"github.com/spf13/viper": true})
+	assert.Len(neighbors, 1)
+	assert.Equal("github.com/spf13/viper", neighbors[0].id)
+
+	// vectors of unstarred are removed.
+	assert.NoError(db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(starredBucketName(src.key))).Delete([]byte("allan/cake"))
+	}))
+	assert.NoError(s.syncVectors(context.Background()))
+	assert.Len(s.semantic.ids, len(docs)-1)
+}
+
+func toFloat64(vector []float32) []float64 {
+	converted := make([]float64, len(vector))
+	for i, v := range vector {
+		converted[i] = float64(v)
+	}
+	return converted
+}